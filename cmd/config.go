@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"search-filter/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Проверка и просмотр конфигурации",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Проверить конфигурацию и вывести все ошибки",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, err := loadConfig()
+		var verr *config.ValidationError
+		if errors.As(err, &verr) {
+			for _, p := range verr.Problems {
+				fmt.Println("❌", p)
+			}
+			return fmt.Errorf("конфигурация некорректна: ошибок %d", len(verr.Problems))
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println("✅ Конфигурация корректна")
+		return nil
+	},
+}
+
+var configPrintCmd = &cobra.Command{
+	Use:   "print",
+	Short: "Вывести итоговую конфигурацию в YAML со скрытыми секретами",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		out, err := cfg.Redacted().YAML()
+		if err != nil {
+			return err
+		}
+		_, err = cmd.OutOrStdout().Write(out)
+		return err
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configValidateCmd, configPrintCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"search-filter/pkg/bundle"
+	"search-filter/pkg/service"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+var filtersCmd = &cobra.Command{
+	Use:   "filters",
+	Short: "Управление фильтрами напрямую через базу данных",
+}
+
+var (
+	exportFormat string
+	exportIDs    []string
+	exportOutput string
+
+	importFile     string
+	importFormat   string
+	importStrategy string
+)
+
+var filtersExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Выгрузить фильтры в JSON или YAML",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ids := make([]uuid.UUID, 0, len(exportIDs))
+		for _, raw := range exportIDs {
+			id, err := uuid.Parse(raw)
+			if err != nil {
+				return fmt.Errorf("invalid id %q: %w", raw, err)
+			}
+			ids = append(ids, id)
+		}
+
+		return withFiltersService(func(svc service.Filters) error {
+			b, err := svc.Export(cmd.Context(), ids)
+			if err != nil {
+				return err
+			}
+
+			w := cmd.OutOrStdout()
+			if exportOutput != "" && exportOutput != "-" {
+				f, err := os.Create(exportOutput)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+			if err := bundle.Encode(w, b, exportFormat); err != nil {
+				return err
+			}
+			cmd.PrintErrf("✅ Выгружено фильтров: %d\n", len(b.Filters))
+			return nil
+		})
+	},
+}
+
+var filtersImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Загрузить фильтры из JSON или YAML",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var (
+			data []byte
+			err  error
+		)
+		if importFile == "" || importFile == "-" {
+			data, err = io.ReadAll(cmd.InOrStdin())
+		} else {
+			data, err = os.ReadFile(importFile)
+		}
+		if err != nil {
+			return err
+		}
+
+		format := importFormat
+		if format == "" {
+			format = bundle.FormatFromPath(importFile)
+		}
+		b, err := bundle.Decode(data, format)
+		if err != nil {
+			return err
+		}
+
+		return withFiltersService(func(svc service.Filters) error {
+			report, err := svc.Import(cmd.Context(), b, service.ConflictStrategy(importStrategy))
+			if err != nil {
+				return err
+			}
+			for _, it := range report.Items {
+				fmt.Printf("%-8s %s → %s  %s\n", it.Action, it.SourceID, it.ID, it.Name)
+			}
+			fmt.Printf("✅ Создано: %d, обновлено: %d, пропущено: %d\n", report.Created, report.Updated, report.Skipped)
+			return nil
+		})
+	},
+}
+
+// withFiltersService открывает БД, собирает сервис фильтров и закрывает БД после fn.
+func withFiltersService(fn func(svc service.Filters) error) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	repo, dbs := openRepository(cfg)
+	if dbs != nil {
+		defer dbs.SQL.Close()
+	}
+
+	// Мутации из CLI должны инвалидировать общий кэш (Redis) работающих серверов.
+	c, closeCache := newCache(cfg)
+	defer closeCache()
+	repo, opts := withCache(c, repo, nil)
+
+	svc, err := newFiltersService(cfg, repo, opts...)
+	if err != nil {
+		return err
+	}
+	return fn(svc)
+}
+
+func init() {
+	filtersExportCmd.Flags().StringVar(&exportFormat, "format", bundle.FormatJSON, "формат выгрузки: json или yaml")
+	filtersExportCmd.Flags().StringSliceVar(&exportIDs, "id", nil, "ID фильтров для выгрузки (по умолчанию все)")
+	filtersExportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "файл для записи (по умолчанию stdout)")
+
+	filtersImportCmd.Flags().StringVarP(&importFile, "file", "f", "", "файл выгрузки (по умолчанию stdin)")
+	filtersImportCmd.Flags().StringVar(&importFormat, "format", "", "формат: json или yaml (по умолчанию по расширению файла)")
+	filtersImportCmd.Flags().StringVar(&importStrategy, "strategy", string(service.ConflictSkip), "при совпадении ID: skip, overwrite или rename")
+
+	filtersCmd.AddCommand(filtersExportCmd, filtersImportCmd)
+	rootCmd.AddCommand(filtersCmd)
+}
@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"search-filter/pkg/models"
+	"search-filter/pkg/service"
+	"search-filter/pkg/types"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+var (
+	outputFormat string
+	filterName   string
+	filterQuery  string
+	queryFile    string
+	deleteForce  bool
+)
+
+var filtersListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Показать все фильтры",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withFiltersService(func(svc service.Filters) error {
+			items, err := svc.List(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printOutput(cmd.OutOrStdout(), items, func(tw *tabwriter.Writer) {
+				fmt.Fprintln(tw, "ID\tNAME\tQUERY")
+				for _, it := range items {
+					fmt.Fprintf(tw, "%s\t%s\t%s\n", it.ID, it.Name, compactJSON(it.Query))
+				}
+			})
+		})
+	},
+}
+
+var filtersGetCmd = &cobra.Command{
+	Use:   "get ID",
+	Short: "Показать фильтр",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := uuid.Parse(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid id %q: %w", args[0], err)
+		}
+		return withFiltersService(func(svc service.Filters) error {
+			f, err := svc.Get(cmd.Context(), id)
+			if err != nil {
+				return err
+			}
+			return printFilter(cmd.OutOrStdout(), f)
+		})
+	},
+}
+
+var filtersCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Создать фильтр",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if filterName == "" {
+			return fmt.Errorf("--name is required")
+		}
+		q, err := readQuery()
+		if err != nil {
+			return err
+		}
+		return withFiltersService(func(svc service.Filters) error {
+			f, err := svc.Create(cmd.Context(), filterName, q)
+			if err != nil {
+				return err
+			}
+			return printFilter(cmd.OutOrStdout(), f)
+		})
+	},
+}
+
+var filtersUpdateCmd = &cobra.Command{
+	Use:   "update ID",
+	Short: "Заменить запрос фильтра",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := uuid.Parse(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid id %q: %w", args[0], err)
+		}
+		q, err := readQuery()
+		if err != nil {
+			return err
+		}
+		return withFiltersService(func(svc service.Filters) error {
+			f, err := svc.Update(cmd.Context(), id, q)
+			if err != nil {
+				return err
+			}
+			return printFilter(cmd.OutOrStdout(), f)
+		})
+	},
+}
+
+var filtersDeleteCmd = &cobra.Command{
+	Use:   "delete ID",
+	Short: "Удалить фильтр",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := uuid.Parse(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid id %q: %w", args[0], err)
+		}
+		return withFiltersService(func(svc service.Filters) error {
+			if err := svc.Delete(cmd.Context(), id, deleteForce); err != nil {
+				return err
+			}
+			fmt.Println("✅ Фильтр удалён")
+			return nil
+		})
+	},
+}
+
+var filtersApplyCmd = &cobra.Command{
+	Use:   "apply ID",
+	Short: "Применить фильтр с подстановкой плейсхолдеров",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := uuid.Parse(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid id %q: %w", args[0], err)
+		}
+		return withFiltersService(func(svc service.Filters) error {
+			q, err := svc.Apply(cmd.Context(), id)
+			if err != nil {
+				return err
+			}
+			return printOutput(cmd.OutOrStdout(), q, func(tw *tabwriter.Writer) {
+				keys := make([]string, 0, len(q))
+				for k := range q {
+					keys = append(keys, k)
+				}
+				sort.Strings(keys)
+
+				fmt.Fprintln(tw, "KEY\tVALUE")
+				for _, k := range keys {
+					fmt.Fprintf(tw, "%s\t%s\n", k, compactJSON(q[k]))
+				}
+			})
+		})
+	},
+}
+
+func readQuery() (types.Query, error) {
+	raw := []byte(filterQuery)
+	if queryFile != "" {
+		var err error
+		if raw, err = os.ReadFile(queryFile); err != nil {
+			return nil, err
+		}
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("--query or --query-file is required")
+	}
+
+	var q types.Query
+	if err := json.Unmarshal(raw, &q); err != nil {
+		return nil, fmt.Errorf("invalid query JSON: %w", err)
+	}
+	if len(q) == 0 {
+		return nil, fmt.Errorf("query must not be empty")
+	}
+	return q, nil
+}
+
+func printFilter(w io.Writer, f *models.Filter) error {
+	return printOutput(w, f, func(tw *tabwriter.Writer) {
+		fmt.Fprintln(tw, "ID\tNAME\tQUERY\tCREATED_AT\tUPDATED_AT")
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			f.ID, f.Name, compactJSON(f.Query), f.CreatedAt.Format(time.RFC3339), f.UpdatedAt.Format(time.RFC3339))
+	})
+}
+
+// printOutput печатает v в формате --format; table рисует переданная функция.
+func printOutput(w io.Writer, v any, table func(tw *tabwriter.Writer)) error {
+	switch outputFormat {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case outputYAML:
+		// Через JSON, чтобы ключи совпадали с json-тегами моделей.
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		var doc any
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			return err
+		}
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+		return enc.Close()
+	case outputTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		table(tw)
+		return tw.Flush()
+	}
+	return fmt.Errorf("unknown output format %q (table|json|yaml)", outputFormat)
+}
+
+func compactJSON(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func init() {
+	for _, c := range []*cobra.Command{filtersListCmd, filtersGetCmd, filtersCreateCmd, filtersUpdateCmd, filtersApplyCmd} {
+		c.Flags().StringVar(&outputFormat, "format", outputTable, "формат вывода: table, json или yaml")
+	}
+	for _, c := range []*cobra.Command{filtersCreateCmd, filtersUpdateCmd} {
+		c.Flags().StringVar(&filterQuery, "query", "", "запрос фильтра в JSON")
+		c.Flags().StringVar(&queryFile, "query-file", "", "файл с запросом фильтра в JSON")
+	}
+	filtersCreateCmd.Flags().StringVar(&filterName, "name", "", "название фильтра")
+	filtersDeleteCmd.Flags().BoolVar(&deleteForce, "force", false, "удалить, даже если на фильтр ссылаются другие")
+
+	filtersCmd.AddCommand(filtersListCmd, filtersGetCmd, filtersCreateCmd, filtersUpdateCmd, filtersDeleteCmd, filtersApplyCmd)
+}
@@ -1,10 +1,15 @@
 package cmd
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
-	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
 
-	"search-filter/pkg/config"
+	"search-filter/pkg/migrate"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	_ "github.com/lib/pq"
@@ -12,7 +17,14 @@ import (
 	"github.com/spf13/cobra"
 )
 
-const migrationsDir = "migrations"
+// defaultCreateDir — каталог, куда create кладёт новые миграции, если --dir не задан.
+const defaultCreateDir = "migrations"
+
+var (
+	migrationsDir string
+	migrateForce  bool
+	verifyTarget  int64
+)
 
 var migrateCmd = &cobra.Command{
 	Use:   "migrate",
@@ -22,42 +34,301 @@ var migrateCmd = &cobra.Command{
 var migrateUpCmd = &cobra.Command{
 	Use:   "up",
 	Short: "Применить все миграции вверх",
-	Run: func(cmd *cobra.Command, args []string) {
-		cfg := config.MustLoad()
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withProvider(cmd.Context(), func(ctx context.Context, db *sql.DB, p *goose.Provider) error {
+			res, err := p.Up(ctx)
+			printResults(res)
+			if err != nil {
+				return fmt.Errorf("goose up: %w", err)
+			}
+			fmt.Println("✅ Миграции применены")
+			return nil
+		})
+	},
+}
 
-		db, err := goose.OpenDBWithDriver("postgres", cfg.PostgresDSN())
+var migrateUpToCmd = &cobra.Command{
+	Use:   "up-to VERSION",
+	Short: "Применить миграции вверх до указанной версии включительно",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		version, err := parseVersion(args[0])
 		if err != nil {
-			log.Fatalf("failed to open DB: %v", err)
-		}
-		defer db.Close()
-
-		if err := goose.Up(db, migrationsDir); err != nil {
-			log.Fatalf("goose up: %v", err)
+			return err
 		}
-		fmt.Println("✅ Миграции применены")
+		return withProvider(cmd.Context(), func(ctx context.Context, db *sql.DB, p *goose.Provider) error {
+			res, err := p.UpTo(ctx, version)
+			printResults(res)
+			if err != nil {
+				return fmt.Errorf("goose up-to: %w", err)
+			}
+			fmt.Printf("✅ Миграции применены до версии %d\n", version)
+			return nil
+		})
 	},
 }
 
 var migrateDownCmd = &cobra.Command{
 	Use:   "down",
 	Short: "Откатить одну миграцию вниз",
-	Run: func(cmd *cobra.Command, args []string) {
-		cfg := config.MustLoad()
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withProvider(cmd.Context(), func(ctx context.Context, db *sql.DB, p *goose.Provider) error {
+			if err := guardDown(ctx, db, p, migrate.LastMigration); err != nil {
+				return err
+			}
 
-		db, err := goose.OpenDBWithDriver("postgres", cfg.PostgresDSN())
+			res, err := p.Down(ctx)
+			if res != nil {
+				printResults([]*goose.MigrationResult{res})
+			}
+			if err != nil {
+				return fmt.Errorf("goose down: %w", err)
+			}
+			fmt.Println("✅ Миграция откатилась")
+			return nil
+		})
+	},
+}
+
+var migrateDownToCmd = &cobra.Command{
+	Use:   "down-to VERSION",
+	Short: "Откатить миграции вниз до указанной версии (она остаётся применённой)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		version, err := parseVersion(args[0])
 		if err != nil {
-			log.Fatalf("failed to open DB: %v", err)
+			return err
 		}
-		defer db.Close()
+		return withProvider(cmd.Context(), func(ctx context.Context, db *sql.DB, p *goose.Provider) error {
+			if err := guardDown(ctx, db, p, version); err != nil {
+				return err
+			}
+
+			res, err := p.DownTo(ctx, version)
+			printResults(res)
+			if err != nil {
+				return fmt.Errorf("goose down-to: %w", err)
+			}
+			fmt.Printf("✅ Миграции откатились до версии %d\n", version)
+			return nil
+		})
+	},
+}
+
+var migrateRedoCmd = &cobra.Command{
+	Use:   "redo",
+	Short: "Откатить и заново применить последнюю миграцию",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withProvider(cmd.Context(), func(ctx context.Context, db *sql.DB, p *goose.Provider) error {
+			if err := guardDown(ctx, db, p, migrate.LastMigration); err != nil {
+				return err
+			}
+
+			down, err := p.Down(ctx)
+			if down != nil {
+				printResults([]*goose.MigrationResult{down})
+			}
+			if err != nil {
+				return fmt.Errorf("goose redo: %w", err)
+			}
+			up, err := p.UpByOne(ctx)
+			if up != nil {
+				printResults([]*goose.MigrationResult{up})
+			}
+			if err != nil {
+				return fmt.Errorf("goose redo: %w", err)
+			}
+			fmt.Println("✅ Миграция применена заново")
+			return nil
+		})
+	},
+}
+
+var migrateResetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Откатить все миграции",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withProvider(cmd.Context(), func(ctx context.Context, db *sql.DB, p *goose.Provider) error {
+			if err := guardDown(ctx, db, p, 0); err != nil {
+				return err
+			}
+
+			res, err := p.DownTo(ctx, 0)
+			printResults(res)
+			if err != nil {
+				return fmt.Errorf("goose reset: %w", err)
+			}
+			fmt.Println("✅ Все миграции откатились")
+			return nil
+		})
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Показать состояние миграций",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withProvider(cmd.Context(), func(ctx context.Context, db *sql.DB, p *goose.Provider) error {
+			statuses, err := p.Status(ctx)
+			if err != nil {
+				return fmt.Errorf("goose status: %w", err)
+			}
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "VERSION\tSTATE\tAPPLIED AT\tMIGRATION")
+			for _, s := range statuses {
+				applied := "-"
+				if !s.AppliedAt.IsZero() {
+					applied = s.AppliedAt.Format(time.RFC3339)
+				}
+				fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", s.Source.Version, s.State, applied, s.Source.Path)
+			}
+			return tw.Flush()
+		})
+	},
+}
+
+var migrateVersionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Показать текущую версию схемы",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withProvider(cmd.Context(), func(ctx context.Context, db *sql.DB, p *goose.Provider) error {
+			current, target, err := p.GetVersions(ctx)
+			if err != nil {
+				return fmt.Errorf("goose version: %w", err)
+			}
+			fmt.Printf("текущая версия: %d, последняя известная: %d\n", current, target)
+			return nil
+		})
+	},
+}
 
-		if err := goose.Down(db, migrationsDir); err != nil {
-			log.Fatalf("goose down: %v", err)
+var migrateVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Проверить, потеряет ли откат данные",
+	Long: `Проверяет, к каким потерям данных приведёт откат до версии --target
+(по умолчанию — откат одной миграции). Команды down, down-to, redo и reset
+выполняют ту же проверку и без --force отказываются от разрушающего отката.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withProvider(cmd.Context(), func(ctx context.Context, db *sql.DB, p *goose.Provider) error {
+			target := verifyTarget
+			if target < 0 {
+				target = migrate.LastMigration
+			}
+			what := fmt.Sprintf("до версии %d", target)
+			if target == migrate.LastMigration {
+				what = "последней применённой миграции"
+			}
+			issues, err := migrate.VerifyDown(ctx, db, p, target)
+			if err != nil {
+				return fmt.Errorf("verify: %w", err)
+			}
+			if len(issues) == 0 {
+				fmt.Printf("✅ Откат %s не теряет данных\n", what)
+				return nil
+			}
+			for _, i := range issues {
+				fmt.Println("⚠️ ", i)
+			}
+			return fmt.Errorf("откат %s приведёт к потере данных", what)
+		})
+	},
+}
+
+var migrateCreateCmd = &cobra.Command{
+	Use:   "create NAME [sql|go]",
+	Short: "Создать файл новой миграции",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		kind := "sql"
+		if len(args) == 2 {
+			kind = args[1]
+		}
+		dir := migrationsDir
+		if dir == "" {
+			dir = defaultCreateDir
 		}
-		fmt.Println("✅ Миграция откатилась")
+		if err := goose.Create(nil, dir, args[0], kind); err != nil {
+			return fmt.Errorf("goose create: %w", err)
+		}
+		return nil
 	},
 }
 
+// withProvider открывает БД из конфигурации и передаёт в fn провайдер миграций
+// из --dir или встроенных в бинарник.
+func withProvider(ctx context.Context, fn func(ctx context.Context, db *sql.DB, p *goose.Provider) error) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	db, err := goose.OpenDBWithDriver("postgres", cfg.PostgresAdminDSN())
+	if err != nil {
+		return fmt.Errorf("failed to open DB: %w", err)
+	}
+	defer db.Close()
+
+	p, err := migrate.NewProvider(db, migrationsDir)
+	if err != nil {
+		return fmt.Errorf("load migrations: %w", err)
+	}
+	return fn(ctx, db, p)
+}
+
+// guardDown отказывает в откате до target, если он приведёт к потере данных и не указан --force.
+func guardDown(ctx context.Context, db *sql.DB, p *goose.Provider, target int64) error {
+	issues, err := migrate.VerifyDown(ctx, db, p, target)
+	if err != nil {
+		return fmt.Errorf("verify: %w", err)
+	}
+	if len(issues) == 0 {
+		return nil
+	}
+	for _, i := range issues {
+		fmt.Println("⚠️ ", i)
+	}
+	if migrateForce {
+		fmt.Println("--force: откат выполняется несмотря на потерю данных")
+		return nil
+	}
+	return fmt.Errorf("откат приведёт к потере данных; проверьте `migrate verify` и повторите с --force")
+}
+
+func printResults(res []*goose.MigrationResult) {
+	for _, r := range res {
+		fmt.Println(r)
+	}
+}
+
+func parseVersion(s string) (int64, error) {
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid version %q", s)
+	}
+	return v, nil
+}
+
 func init() {
-	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd)
+	migrateCmd.PersistentFlags().StringVar(&migrationsDir, "dir", "",
+		"каталог с миграциями (по умолчанию встроенные в бинарник; для create — "+defaultCreateDir+")")
+	migrateCmd.PersistentFlags().BoolVar(&migrateForce, "force", false,
+		"выполнить откат, даже если он приведёт к потере данных")
+	migrateVerifyCmd.Flags().Int64Var(&verifyTarget, "target", -1,
+		"версия, до которой проверяется откат (по умолчанию — откат последней применённой миграции)")
+
+	migrateCmd.AddCommand(
+		migrateUpCmd, migrateUpToCmd,
+		migrateDownCmd, migrateDownToCmd,
+		migrateRedoCmd, migrateResetCmd,
+		migrateStatusCmd, migrateVersionCmd, migrateVerifyCmd,
+		migrateCreateCmd,
+	)
 	rootCmd.AddCommand(migrateCmd)
 }
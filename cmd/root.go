@@ -2,8 +2,12 @@ package cmd
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 
+	"search-filter/pkg/config"
+	"search-filter/pkg/logging"
+
 	"github.com/spf13/cobra"
 )
 
@@ -11,6 +15,10 @@ var rootCmd = &cobra.Command{
 	Use:   "search-filter",
 	Short: "Search Filter Service CLI",
 	Long:  `CLI для управления сервисом search-filter (запуск сервера, миграции базы данных и др).`,
+	// Справка по флагам конфигурации длинная и после ошибки выполнения только мешает;
+	// саму ошибку печатает Execute.
+	SilenceUsage:  true,
+	SilenceErrors: true,
 }
 
 func Execute() {
@@ -19,3 +27,22 @@ func Execute() {
 		os.Exit(1)
 	}
 }
+
+// loadConfig читает конфигурацию с учётом глобальных флагов командной строки
+// и настраивает по ней slog; вывод пакета log тоже идёт через slog.
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.Load(config.WithFlags(rootCmd.PersistentFlags()))
+	if err != nil {
+		return nil, err
+	}
+	logger, err := logging.New(os.Stderr, cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(logger)
+	return cfg, nil
+}
+
+func init() {
+	config.RegisterFlags(rootCmd.PersistentFlags())
+}
@@ -2,16 +2,28 @@ package cmd
 
 import (
 	"context"
-	"log"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
+	"search-filter/pkg/backend"
+	"search-filter/pkg/cache"
 	"search-filter/pkg/config"
+	"search-filter/pkg/health"
 	httpapi "search-filter/pkg/http"
+	"search-filter/pkg/metrics"
+	"search-filter/pkg/migrate"
+	"search-filter/pkg/notify"
 	"search-filter/pkg/repository"
 	"search-filter/pkg/service"
 	"search-filter/pkg/storage"
+	"search-filter/pkg/tracing"
+	"search-filter/pkg/webhook"
 
 	"github.com/spf13/cobra"
 )
@@ -20,54 +32,326 @@ var serveCmd = &cobra.Command{
 	Use:   "serve",
 	Short: "Запустить HTTP сервер",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg := config.MustLoad()
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
 
-		dbs := storage.MustInitPostgres(cfg.PostgresDSN())
+		shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+			Exporter:    cfg.TracingExporter,
+			Endpoint:    cfg.TracingOTLPEndpoint,
+			Insecure:    cfg.TracingOTLPInsecure,
+			SampleRatio: cfg.TracingSampleRatio,
+		})
+		if err != nil {
+			slog.Error("failed to init tracing", "error", err)
+			return err
+		}
 		defer func() {
-			if err := dbs.SQL.Close(); err != nil {
-				log.Printf("db close error: %v", err)
+			// ctx к этому моменту отменён сигналом, поэтому выгрузке спанов нужен свой срок.
+			sctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdownTracing(sctx); err != nil {
+				slog.Error("tracing shutdown", "error", err)
 			}
 		}()
 
-		repo := repository.NewPostgresRepository(dbs.Reform)
+		repo, dbs := openRepository(cfg)
+		if dbs != nil {
+			defer func() {
+				if err := dbs.SQL.Close(); err != nil {
+					slog.Error("db close", "error", err)
+				}
+			}()
+		}
 
-		loc, err := time.LoadLocation(cfg.Timezone)
-		if err != nil {
-			log.Fatalf("invalid timezone %q: %v", cfg.Timezone, err)
+		if cmd.Flags().Changed("migrate") {
+			cfg.AutoMigrate = serveMigrate
 		}
-		svc, err := service.NewFiltersService(repo, loc, 42)
+		if cfg.AutoMigrate && cfg.StorageDriver == config.StoragePostgres {
+			if err := autoMigrate(cmd.Context(), cfg); err != nil {
+				slog.Error("auto-migrate failed", "error", err)
+				return err
+			}
+		}
+
+		sb, err := newSearchBackend(cfg)
 		if err != nil {
-			log.Printf("failed to init service: %v", err)
+			slog.Error("failed to init search backend", "error", err)
 			return err
 		}
+		var opts []service.Option
+		if sb != nil {
+			opts = append(opts, service.WithBackend(sb))
+		}
 
-		srv := httpapi.NewServer(cfg, svc)
-		addr := ":8080"
+		// Фоновые задачи останавливаются после HTTP-сервера: дорабатывающие запросы
+		// ещё пишут события для вебхуков и ждут уведомлений LISTEN.
+		wctx, stopWorkers := context.WithCancel(context.Background())
+		defer stopWorkers()
+		var workers sync.WaitGroup
 
-		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
-		defer stop()
+		var srvOpts []httpapi.Option
+		// Ленту событий ведёт только PostgresRepository; проверяем до обёртки кэшем.
+		if el, ok := repo.(repository.EventLog); ok {
+			events, err := service.NewEventsService(el)
+			if err != nil {
+				return err
+			}
+			srvOpts = append(srvOpts, httpapi.WithEvents(events))
+		}
+		if len(cfg.Webhooks) > 0 {
+			d := newDispatcher(cfg, dbs)
+			workers.Go(func() { d.Run(wctx) })
+			srvOpts = append(srvOpts, httpapi.WithWebhooks(d))
+		}
+
+		m := metrics.New()
+		srvOpts = append(srvOpts, httpapi.WithMetrics(m))
+		if dbs != nil {
+			m.RegisterDB(dbs.SQL, cfg.StorageDriver)
+		}
+		// Замеряем само хранилище, без попаданий в кэш.
+		repo = repository.NewInstrumentedRepository(repo, m.ObserveRepository)
+		opts = append(opts, service.WithRenderObserver(m.ObserveRender))
+
+		c, closeCache := newCache(cfg)
+		defer func() {
+			if err := closeCache(); err != nil {
+				slog.Error("cache close", "error", err)
+			}
+		}()
+		repo, opts = withCache(c, repo, opts)
+
+		svc, err := newFiltersService(cfg, repo, opts...)
+		if err != nil {
+			slog.Error("failed to init service", "error", err)
+			return err
+		}
+		svc = service.NewTracedFilters(svc)
+
+		if c != nil {
+			srvOpts = append(srvOpts, httpapi.WithCache(c))
+			m.RegisterCache(c)
+		}
+		hc, err := newHealthChecker(cfg, dbs, c, sb)
+		if err != nil {
+			return err
+		}
+		srvOpts = append(srvOpts, httpapi.WithHealth(hc))
+		srv := httpapi.NewServer(cfg, svc, srvOpts...)
+		if cfg.StorageDriver == config.StoragePostgres {
+			rotated := make(chan *config.Config, 1)
+			workers.Go(func() { listenFilterChanges(wctx, cfg, rotated, c, srv) })
+			if cfg.HasSecretRefs() && cfg.SecretsRefreshInterval > 0 {
+				workers.Go(func() { rotateSecrets(wctx, cfg, dbs, rotated) })
+			}
+		}
 
 		errCh := make(chan error, 1)
-		go func() { errCh <- srv.Run(addr) }()
+		go func() { errCh <- srv.Run() }()
 
 		select {
 		case <-ctx.Done():
-			log.Println("shutdown signal received")
+			slog.Info("shutdown signal received")
 		case err := <-errCh:
 			if err != nil {
-				log.Printf("server run error: %v", err)
+				slog.Error("server run", "error", err)
 				return err
 			}
 		}
+		// Повторный сигнал завершает процесс сразу, не дожидаясь остановки.
+		stop()
 
-		if err := srv.Shutdown(context.Background()); err != nil {
-			log.Printf("shutdown error: %v", err)
-		}
-		log.Println("server stopped gracefully")
-		return nil
+		return shutdown(cfg, hc, srv, stopWorkers, &workers)
 	},
 }
 
+// shutdown снимает готовность, через shutdown_delay останавливает HTTP-сервер,
+// дожидаясь обрабатываемых запросов, затем фоновые задачи. На всё отводится
+// shutdown_timeout; соединения с БД и кэшем закрываются после возврата.
+func shutdown(cfg *config.Config, hc *health.Checker, srv *httpapi.Server, stopWorkers context.CancelFunc, workers *sync.WaitGroup) error {
+	hc.Shutdown()
+	if cfg.ShutdownDelay > 0 {
+		slog.Info("readiness withdrawn, waiting before drain", "delay", cfg.ShutdownDelay)
+		time.Sleep(cfg.ShutdownDelay)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	slog.Info("draining requests", "in_flight", srv.InFlight(), "timeout", cfg.ShutdownTimeout)
+	drainErr := srv.Shutdown(ctx)
+	if drainErr != nil {
+		slog.Error("drain timed out", "error", drainErr)
+	}
+
+	stopWorkers()
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		slog.Error("background workers did not stop in time")
+		return fmt.Errorf("shutdown: background workers did not stop within %s", cfg.ShutdownTimeout)
+	}
+
+	if drainErr != nil {
+		return fmt.Errorf("shutdown: %w", drainErr)
+	}
+	slog.Info("server stopped gracefully")
+	return nil
+}
+
+func newSearchBackend(cfg *config.Config) (backend.Backend, error) {
+	switch cfg.SearchBackend {
+	case config.SearchBackendMemory:
+		if cfg.SearchBackendDataset == "" {
+			return backend.NewMemory(nil, cfg.ElasticsearchMapping()), nil
+		}
+		return backend.LoadMemory(cfg.SearchBackendDataset, cfg.ElasticsearchMapping())
+	case config.SearchBackendElasticsearch:
+		return backend.NewElasticsearch(cfg.ElasticsearchURL, cfg.ElasticsearchIndex, cfg.ElasticsearchMapping(), nil), nil
+	}
+	return nil, nil
+}
+
+// newHealthChecker собирает проверки для /readyz: соединение с БД, версию схемы
+// Postgres, Redis и поисковый бэкенд, если они настроены. Схема новее бинарника
+// готовности не снимает: так бывает во время выкатки, пока работают старые реплики.
+func newHealthChecker(cfg *config.Config, dbs *storage.DBs, c *cache.Cache, sb backend.Backend) (*health.Checker, error) {
+	h := health.New(cfg.HealthCheckTimeout)
+	if dbs != nil {
+		h.Add(cfg.StorageDriver, dbs.SQL.PingContext)
+	}
+	if cfg.StorageDriver == config.StoragePostgres {
+		mc, err := migrate.NewChecker(dbs.SQL)
+		if err != nil {
+			return nil, err
+		}
+		h.Add("migrations", func(ctx context.Context) error {
+			err := mc.Check(ctx)
+			if errors.Is(err, migrate.ErrSchemaAhead) {
+				return health.Warn(err)
+			}
+			return err
+		})
+	}
+	if c != nil {
+		h.Add("cache", c.Ping)
+	}
+	if p, ok := sb.(interface{ Ping(context.Context) error }); ok {
+		h.Add("search_backend", p.Ping)
+	}
+	return h, nil
+}
+
+// autoMigrate применяет миграции через отдельное соединение без statement_timeout.
+func autoMigrate(ctx context.Context, cfg *config.Config) error {
+	db, err := sql.Open("postgres", cfg.PostgresAdminDSN())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	res, err := migrate.Up(ctx, db)
+	if err != nil {
+		return err
+	}
+	for _, r := range res {
+		slog.Info("migration applied", "migration", r.String())
+	}
+	return nil
+}
+
+func newDispatcher(cfg *config.Config, dbs *storage.DBs) *webhook.Dispatcher {
+	endpoints := make([]webhook.Endpoint, 0, len(cfg.Webhooks))
+	for _, w := range cfg.Webhooks {
+		endpoints = append(endpoints, webhook.Endpoint{URL: w.URL, Secret: w.Secret, Events: w.Events})
+	}
+	return webhook.NewDispatcher(dbs.SQL, endpoints,
+		webhook.WithMaxAttempts(cfg.WebhookMaxAttempts),
+		webhook.WithTimeout(cfg.WebhookTimeout),
+	)
+}
+
+// listenFilterChanges по NOTIFY filters_changed сбрасывает кэш и будит SSE-подписчиков,
+// чтобы изменения, сделанные другими репликами или прямо в БД, доходили и до этой.
+// После ротации секретов подписка переоткрывается с новой строкой подключения.
+func listenFilterChanges(ctx context.Context, cfg *config.Config, rotated <-chan *config.Config, c *cache.Cache, srv *httpapi.Server) {
+	changed := func(ctx context.Context, _ *notify.Change) {
+		if c != nil {
+			c.Invalidate(ctx)
+		}
+		srv.NotifyEvents()
+	}
+	for {
+		lctx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func(dsn string) {
+			defer close(done)
+			if err := notify.Listen(lctx, dsn, notify.ChannelFiltersChanged, changed); err != nil {
+				slog.Error("filters_changed listener stopped", "error", err)
+			}
+		}(cfg.PostgresAdminDSN())
+
+		select {
+		case <-ctx.Done():
+			cancel()
+			<-done
+			return
+		case cfg = <-rotated:
+			cancel()
+			<-done
+			// Уведомления между подписками могли потеряться.
+			changed(ctx, nil)
+		}
+	}
+}
+
+// rotateSecrets раз в secrets_refresh_interval перечитывает секреты и, если изменилась
+// строка подключения к Postgres, переключает на неё новые соединения пула и подписку LISTEN.
+func rotateSecrets(ctx context.Context, cfg *config.Config, dbs *storage.DBs, rotated chan<- *config.Config) {
+	ticker := time.NewTicker(cfg.SecretsRefreshInterval)
+	defer ticker.Stop()
+
+	dsn := cfg.PostgresDSN()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		fresh, err := cfg.Refreshed(ctx)
+		if err != nil {
+			slog.Error("secrets refresh failed", "error", err)
+			continue
+		}
+		if fresh.PostgresDSN() == dsn {
+			continue
+		}
+		dsn = fresh.PostgresDSN()
+		dbs.SetDSN(dsn)
+		select {
+		case rotated <- fresh:
+		case <-ctx.Done():
+			return
+		}
+		slog.Info("postgres credentials rotated")
+	}
+}
+
+var serveMigrate bool
+
 func init() {
+	serveCmd.Flags().BoolVar(&serveMigrate, "migrate", false,
+		"применить встроенные миграции перед запуском (переопределяет auto_migrate)")
 	rootCmd.AddCommand(serveCmd)
 }
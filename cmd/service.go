@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"search-filter/pkg/cache"
+	"search-filter/pkg/config"
+	"search-filter/pkg/repository"
+	"search-filter/pkg/service"
+	"search-filter/pkg/storage"
+
+	"github.com/redis/go-redis/v9"
+	reform "gopkg.in/reform.v1"
+)
+
+// currentUserID — пользователь для {{current_user}}, пока в сервисе нет аутентификации.
+const currentUserID = 42
+
+// openRepository открывает хранилище, выбранное storage_driver.
+// Для memory DBs равен nil.
+func openRepository(cfg *config.Config) (repository.Repository, *storage.DBs) {
+	switch cfg.StorageDriver {
+	case config.StorageMemory:
+		return repository.NewMemoryRepository(), nil
+	case config.StorageSQLite:
+		dbs := storage.MustInitSQLite(cfg.SQLitePath, sqlLogger(cfg))
+		return repository.NewSQLiteRepository(dbs.Reform), dbs
+	}
+	dbs := storage.MustInitPostgres(cfg.PostgresDSN(), storage.Pool{
+		MaxOpenConns:    cfg.PostgresMaxOpenConns,
+		MaxIdleConns:    cfg.PostgresMaxIdleConns,
+		ConnMaxLifetime: cfg.PostgresConnMaxLifetime,
+		ConnMaxIdleTime: cfg.PostgresConnMaxIdleTime,
+	}, sqlLogger(cfg))
+	return repository.NewPostgresRepository(dbs.Reform), dbs
+}
+
+// sqlLogger возвращает журнал SQL, если он включён log_sql; иначе nil.
+func sqlLogger(cfg *config.Config) reform.Logger {
+	if !cfg.LogSQL {
+		return nil
+	}
+	return storage.NewSQLLogger(slog.Default(), cfg.LogSQLSampleRate)
+}
+
+func newFiltersService(cfg *config.Config, repo repository.Repository, opts ...service.Option) (service.Filters, error) {
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", cfg.Timezone, err)
+	}
+	return service.NewFiltersService(repo, loc, currentUserID, opts...)
+}
+
+// newCache создаёт кэш, выбранный cache_driver; nil, если кэш выключен.
+// Возвращаемая функция закрывает соединение с Redis.
+func newCache(cfg *config.Config) (*cache.Cache, func() error) {
+	switch cfg.CacheDriver {
+	case config.CacheMemory:
+		return cache.New(cache.NewLRU(cfg.CacheSize), "search-filter:", cfg.CacheTTL), func() error { return nil }
+	case config.CacheRedis:
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.CacheRedisAddr,
+			Password: cfg.CacheRedisPassword,
+			DB:       cfg.CacheRedisDB,
+		})
+		return cache.New(cache.NewRedis(client), "search-filter:", cfg.CacheTTL), client.Close
+	}
+	return nil, func() error { return nil }
+}
+
+// withCache оборачивает репозиторий кэшем и добавляет кэш отрендеренных запросов.
+func withCache(c *cache.Cache, repo repository.Repository, opts []service.Option) (repository.Repository, []service.Option) {
+	if c == nil {
+		return repo, opts
+	}
+	return repository.NewCachedRepository(repo, c), append(opts, service.WithCache(c))
+}
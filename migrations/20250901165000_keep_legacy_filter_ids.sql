@@ -0,0 +1,20 @@
+-- +goose Up
+-- Миграция 20250901170000 заменяет BIGINT id фильтров случайными UUID. Прежний id
+-- сохраняется в legacy_id, чтобы 20250901170100 заменила случайные UUID
+-- детерминированными. Если id уже UUID (20250901170000 применена до появления
+-- этой миграции, и она выполняется вне очереди), сохранять нечего.
+-- +goose StatementBegin
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'filters'
+                 AND column_name = 'id' AND data_type = 'bigint') THEN
+        ALTER TABLE filters ADD COLUMN IF NOT EXISTS legacy_id BIGINT;
+        UPDATE filters SET legacy_id = id;
+    END IF;
+END
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+ALTER TABLE filters DROP COLUMN IF EXISTS legacy_id;
@@ -1,12 +1,82 @@
 -- +goose Up
+-- legacy_id (см. 20250901165000) дополняется фильтрами, созданными после отката
+-- этой миграции, чтобы 20250901170100 выдала UUID и им.
+-- +goose StatementBegin
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'filters'
+                 AND column_name = 'legacy_id') THEN
+        UPDATE filters SET legacy_id = id WHERE legacy_id IS NULL;
+    END IF;
+END
+$$;
+-- +goose StatementEnd
+
 ALTER TABLE filters
     ALTER COLUMN id DROP DEFAULT,
     ALTER COLUMN id TYPE UUID USING gen_random_uuid(),
     ALTER COLUMN id SET DEFAULT gen_random_uuid();
 
 -- +goose Down
--- При откате потеряются данные, потому что UUID → BIGINT не конвертируется обратно.
-ALTER TABLE filters
-    ALTER COLUMN id DROP DEFAULT,
-    ALTER COLUMN id TYPE BIGINT USING 0,
-    ALTER COLUMN id ADD GENERATED BY DEFAULT AS IDENTITY;
\ No newline at end of file
+-- BIGINT id восстанавливаются из legacy_id (его заполняет откат 20250901170100),
+-- вместе со ссылками в filter_dependencies, если таблица ещё есть. Фильтры,
+-- созданные уже с UUID, получают новые id после наибольшего. legacy_id остаётся,
+-- как после 20250901165000, чтобы повторный up вернул фильтрам те же UUID.
+-- +goose StatementBegin
+DO $$
+DECLARE
+    deps     BOOLEAN := to_regclass('filter_dependencies') IS NOT NULL;
+    deps_fk  BOOLEAN := false;
+BEGIN
+    ALTER TABLE filters ADD COLUMN IF NOT EXISTS legacy_id BIGINT;
+    UPDATE filters AS f SET legacy_id = n.legacy_id
+    FROM (SELECT id,
+                 (SELECT coalesce(max(legacy_id), 0) FROM filters)
+                     + row_number() OVER (ORDER BY created_at, id) AS legacy_id
+          FROM filters
+          WHERE legacy_id IS NULL) AS n
+    WHERE f.id = n.id;
+
+    -- Внешние ключи filter_dependencies не дают сменить тип filters.id: ссылки
+    -- переводятся в BIGINT отдельными столбцами, старые удаляются вместе с ключами.
+    IF deps THEN
+        deps_fk := EXISTS (SELECT 1 FROM pg_constraint
+                            WHERE conrelid = 'filter_dependencies'::regclass
+                              AND conname = 'filter_dependencies_depends_on_fkey');
+        ALTER TABLE filter_dependencies
+            ADD COLUMN legacy_filter_id BIGINT,
+            ADD COLUMN legacy_depends_on BIGINT;
+        UPDATE filter_dependencies AS d SET legacy_filter_id = f.legacy_id FROM filters AS f WHERE f.id = d.filter_id;
+        UPDATE filter_dependencies AS d SET legacy_depends_on = f.legacy_id FROM filters AS f WHERE f.id = d.depends_on;
+        DELETE FROM filter_dependencies WHERE legacy_filter_id IS NULL OR legacy_depends_on IS NULL;
+        ALTER TABLE filter_dependencies DROP COLUMN filter_id, DROP COLUMN depends_on;
+        ALTER TABLE filter_dependencies RENAME COLUMN legacy_filter_id TO filter_id;
+        ALTER TABLE filter_dependencies RENAME COLUMN legacy_depends_on TO depends_on;
+    END IF;
+
+    ALTER TABLE filters
+        ALTER COLUMN id DROP DEFAULT,
+        ALTER COLUMN id TYPE BIGINT USING legacy_id;
+    -- Последовательность BIGSERIAL переживает переход на UUID; создаётся, только если её нет.
+    CREATE SEQUENCE IF NOT EXISTS filters_id_seq OWNED BY filters.id;
+    PERFORM setval('filters_id_seq', coalesce(max(id), 0) + 1, false) FROM filters;
+    ALTER TABLE filters ALTER COLUMN id SET DEFAULT nextval('filters_id_seq');
+
+    IF deps THEN
+        ALTER TABLE filter_dependencies
+            ALTER COLUMN filter_id SET NOT NULL,
+            ALTER COLUMN depends_on SET NOT NULL,
+            ADD PRIMARY KEY (filter_id, depends_on),
+            ADD FOREIGN KEY (filter_id) REFERENCES filters (id) ON DELETE CASCADE;
+        CREATE INDEX IF NOT EXISTS filter_dependencies_depends_on_idx ON filter_dependencies (depends_on);
+        IF deps_fk THEN
+            ALTER TABLE filter_dependencies
+                ADD CONSTRAINT filter_dependencies_depends_on_fkey
+                    FOREIGN KEY (depends_on) REFERENCES filters (id)
+                    DEFERRABLE INITIALLY DEFERRED;
+        END IF;
+    END IF;
+END
+$$;
+-- +goose StatementEnd
@@ -0,0 +1,57 @@
+-- +goose Up
+-- Фильтры, сохранившие legacy_id (см. 20250901165000), получают детерминированные
+-- UUID v3: MD5 от пространства имён 0b0266d7-9303-43ed-9bf5-3cb5285036da и
+-- десятичной записи старого id, как migrate.LegacyFilterID в Go. Соответствие
+-- сохраняется в filter_id_map для внешних ссылок на старые id. В базах, где
+-- 20250901170000 применили раньше, legacy_id нет: прежние id там уже потеряны.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION filter_legacy_uuid(old_id BIGINT) RETURNS UUID
+    LANGUAGE sql IMMUTABLE STRICT AS
+$$
+SELECT encode(
+           set_byte(set_byte(h, 6, (get_byte(h, 6) & 15) | 48), 8, (get_byte(h, 8) & 63) | 128),
+           'hex')::uuid
+FROM (SELECT decode(md5(decode('0b0266d7930343ed9bf53cb5285036da', 'hex') || convert_to(old_id::text, 'UTF8')),
+                    'hex') AS h) AS digest
+$$;
+-- +goose StatementEnd
+
+CREATE TABLE IF NOT EXISTS filter_id_map (
+    old_id  BIGINT PRIMARY KEY,
+    new_id  UUID   NOT NULL UNIQUE
+);
+
+-- +goose StatementBegin
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'filters'
+                 AND column_name = 'legacy_id') THEN
+        INSERT INTO filter_id_map (old_id, new_id)
+        SELECT legacy_id, filter_legacy_uuid(legacy_id) FROM filters WHERE legacy_id IS NOT NULL;
+
+        -- UUID v3 не пересекаются со случайными v4, поэтому замена не нарушает ключ.
+        UPDATE filters SET id = filter_legacy_uuid(legacy_id) WHERE legacy_id IS NOT NULL;
+
+        ALTER TABLE filters DROP COLUMN legacy_id;
+    END IF;
+END
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- Прежние id возвращаются в legacy_id из filter_id_map: по ним откат
+-- 20250901170000 восстановит BIGINT id, а повторный up — те же UUID.
+-- +goose StatementBegin
+DO $$
+BEGIN
+    IF to_regclass('filter_id_map') IS NOT NULL THEN
+        ALTER TABLE filters ADD COLUMN IF NOT EXISTS legacy_id BIGINT;
+        UPDATE filters AS f SET legacy_id = m.old_id FROM filter_id_map AS m WHERE m.new_id = f.id;
+    END IF;
+END
+$$;
+-- +goose StatementEnd
+
+DROP TABLE IF EXISTS filter_id_map;
+DROP FUNCTION IF EXISTS filter_legacy_uuid(BIGINT);
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS filter_dependencies (
+    filter_id   UUID NOT NULL REFERENCES filters (id) ON DELETE CASCADE,
+    depends_on  UUID NOT NULL,
+    PRIMARY KEY (filter_id, depends_on)
+);
+
+-- Внешний ключ на depends_on (отложенный) добавляет 20261020100000. Принудительное
+-- удаление фильтра убирает его строки зависимостей, но ссылки в запросах других
+-- фильтров остаются, и Apply сообщает о них как о ненайденных.
+CREATE INDEX IF NOT EXISTS filter_dependencies_depends_on_idx ON filter_dependencies (depends_on);
+
+-- +goose Down
+DROP TABLE IF EXISTS filter_dependencies;
@@ -0,0 +1,27 @@
+-- +goose Up
+-- Каждое изменение filters рассылает NOTIFY filters_changed, чтобы реплики
+-- сбрасывали локальные кэши. Триггер срабатывает и для правок в обход сервиса.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_filters_changed() RETURNS trigger AS $$
+DECLARE
+    changed UUID;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed := OLD.id;
+    ELSE
+        changed := NEW.id;
+    END IF;
+    PERFORM pg_notify('filters_changed', json_build_object('op', lower(TG_OP), 'id', changed)::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP TRIGGER IF EXISTS filters_changed ON filters;
+CREATE TRIGGER filters_changed
+    AFTER INSERT OR UPDATE OR DELETE ON filters
+    FOR EACH ROW EXECUTE FUNCTION notify_filters_changed();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS filters_changed ON filters;
+DROP FUNCTION IF EXISTS notify_filters_changed();
@@ -0,0 +1,37 @@
+-- +goose Up
+-- Outbox событий жизненного цикла фильтров. Пишется в той же транзакции, что и
+-- изменение, поэтому событие есть тогда и только тогда, когда изменение закоммичено.
+CREATE TABLE IF NOT EXISTS filter_events (
+    id             BIGSERIAL   PRIMARY KEY,
+    type           TEXT        NOT NULL,
+    filter_id      UUID        NOT NULL,
+    payload        JSONB,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    -- Транзакция-автор: события отдаются читателям только после завершения всех
+    -- транзакций, которые ещё могут закоммитить меньший id (см. EventLog.Events).
+    tx_id          XID8        NOT NULL DEFAULT pg_current_xact_id(),
+    -- Когда по событию созданы доставки вебхуков; NULL — ещё не разослано.
+    dispatched_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS filter_events_undispatched_idx ON filter_events (id) WHERE dispatched_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id               BIGSERIAL   PRIMARY KEY,
+    webhook          TEXT        NOT NULL,
+    event_id         BIGINT      NOT NULL REFERENCES filter_events (id) ON DELETE CASCADE,
+    attempts         INT         NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_error       TEXT,
+    delivered_at     TIMESTAMPTZ,
+    -- Непустое значение — доставка исчерпала попытки и лежит в dead-letter.
+    dead_at          TIMESTAMPTZ,
+    UNIQUE (webhook, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at)
+    WHERE delivered_at IS NULL AND dead_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS filter_events;
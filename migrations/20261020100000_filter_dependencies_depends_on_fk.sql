@@ -0,0 +1,15 @@
+-- +goose Up
+-- Ссылка на несуществующий фильтр отклоняется и на уровне БД. Ограничение
+-- отложенное: пакет и импорт создают фильтры в одной транзакции в произвольном
+-- порядке, и ссылка может опередить фильтр, на который указывает. Принудительное
+-- удаление сначала убирает строки зависимостей, поэтому ограничению не мешает.
+DELETE FROM filter_dependencies AS d
+WHERE NOT EXISTS (SELECT 1 FROM filters AS f WHERE f.id = d.depends_on);
+
+ALTER TABLE filter_dependencies
+    ADD CONSTRAINT filter_dependencies_depends_on_fkey
+        FOREIGN KEY (depends_on) REFERENCES filters (id)
+        DEFERRABLE INITIALLY DEFERRED;
+
+-- +goose Down
+ALTER TABLE filter_dependencies DROP CONSTRAINT IF EXISTS filter_dependencies_depends_on_fkey;
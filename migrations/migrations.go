@@ -0,0 +1,8 @@
+// Package migrations встраивает SQL-миграции в бинарник,
+// чтобы команда migrate не зависела от рабочего каталога.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
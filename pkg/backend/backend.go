@@ -0,0 +1,45 @@
+package backend
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"search-filter/pkg/types"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type Document map[string]any
+
+type Page struct {
+	Cursor string
+	Limit  int
+}
+
+// Backend выполняет отрендеренный запрос фильтра.
+// Пустой курсор в ответе означает, что результатов больше нет.
+type Backend interface {
+	Search(ctx context.Context, query types.Query, page Page) ([]Document, string, error)
+}
+
+func encodeOffset(off int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(off)))
+}
+
+func decodeOffset(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidCursor, err)
+	}
+	off, err := strconv.Atoi(string(b))
+	if err != nil || off < 0 {
+		return 0, fmt.Errorf("%w: bad offset", ErrInvalidCursor)
+	}
+	return off, nil
+}
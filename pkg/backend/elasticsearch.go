@@ -0,0 +1,126 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"search-filter/pkg/elastic"
+	"search-filter/pkg/types"
+)
+
+// Elasticsearch проксирует запросы в индекс Elasticsearch/OpenSearch через _search.
+type Elasticsearch struct {
+	baseURL string
+	index   string
+	mapping elastic.Mapping
+	client  *http.Client
+}
+
+func NewElasticsearch(baseURL, index string, mapping elastic.Mapping, client *http.Client) *Elasticsearch {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Elasticsearch{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		index:   index,
+		mapping: mapping,
+		client:  client,
+	}
+}
+
+type esSearchResponse struct {
+	Hits struct {
+		Total struct {
+			Value int `json:"value"`
+		} `json:"total"`
+		Hits []struct {
+			ID     string   `json:"_id"`
+			Source Document `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+func (e *Elasticsearch) Search(ctx context.Context, query types.Query, page Page) ([]Document, string, error) {
+	off, err := decodeOffset(page.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	body, err := elastic.Compile(query, e.mapping)
+	if err != nil {
+		return nil, "", err
+	}
+	body["from"] = off
+	if page.Limit > 0 {
+		body["size"] = page.Limit
+	}
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil, "", fmt.Errorf("marshal search body: %w", err)
+	}
+
+	u := e.baseURL + "/" + url.PathEscape(e.index) + "/_search"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(raw))
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("elasticsearch search: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, "", fmt.Errorf("elasticsearch search: status %d: %s", resp.StatusCode, msg)
+	}
+
+	var res esSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, "", fmt.Errorf("elasticsearch search: decode: %w", err)
+	}
+
+	docs := make([]Document, 0, len(res.Hits.Hits))
+	for _, h := range res.Hits.Hits {
+		d := h.Source
+		if d == nil {
+			d = Document{}
+		}
+		if _, ok := d["_id"]; !ok {
+			d["_id"] = h.ID
+		}
+		docs = append(docs, d)
+	}
+
+	next := ""
+	if end := off + len(docs); len(docs) > 0 && end < res.Hits.Total.Value {
+		next = encodeOffset(end)
+	}
+	return docs, next, nil
+}
+
+// Ping проверяет, что кластер отвечает и индекс существует.
+func (e *Elasticsearch) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, e.baseURL+"/"+url.PathEscape(e.index), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("elasticsearch ping: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("elasticsearch ping: index %s: status %d", e.index, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"search-filter/pkg/elastic"
+	"search-filter/pkg/types"
+)
+
+func TestElasticsearchSearch(t *testing.T) {
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.EscapedPath() != "/my%20index/_search" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %s", ct)
+		}
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+			return
+		}
+		bodies = append(bodies, body)
+
+		from := int(body["from"].(float64))
+		hits := []map[string]any{}
+		for i := from; i < from+2 && i < 3; i++ {
+			hit := map[string]any{"_id": string(rune('a' + i)), "_source": map[string]any{"n": i}}
+			if i == 2 {
+				delete(hit, "_source")
+			}
+			hits = append(hits, hit)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"hits": map[string]any{"total": map[string]any{"value": 3}, "hits": hits}})
+	}))
+	defer srv.Close()
+
+	es := NewElasticsearch(srv.URL+"/", "my index", elastic.DefaultMapping(), srv.Client())
+	ctx := context.Background()
+
+	docs, next, err := es.Search(ctx, types.Query{"tags": []any{"golang"}}, Page{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 || docs[0]["_id"] != "a" || docs[1]["n"] != 1.0 || next == "" {
+		t.Fatalf("first page = %v, next %q", docs, next)
+	}
+	q, _ := json.Marshal(bodies[0]["query"])
+	if string(q) != `{"bool":{"filter":[{"terms":{"tags":["golang"]}}]}}` || bodies[0]["size"] != 2.0 || bodies[0]["from"] != 0.0 {
+		t.Errorf("request body = %v", bodies[0])
+	}
+
+	docs, next, err = es.Search(ctx, types.Query{}, Page{Cursor: next, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 1 || docs[0]["_id"] != "c" || next != "" {
+		t.Errorf("last page = %v, next %q", docs, next)
+	}
+	if bodies[1]["from"] != 2.0 {
+		t.Errorf("from = %v, want 2", bodies[1]["from"])
+	}
+}
+
+func TestElasticsearchErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/broken/_search":
+			w.Write([]byte("{"))
+		default:
+			http.Error(w, `{"error":"index_not_found_exception"}`, http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	_, _, err := NewElasticsearch(srv.URL, "missing", nil, nil).Search(ctx, types.Query{}, Page{})
+	if err == nil || !strings.Contains(err.Error(), "status 404") || !strings.Contains(err.Error(), "index_not_found") {
+		t.Errorf("status error = %v", err)
+	}
+	if _, _, err := NewElasticsearch(srv.URL, "broken", nil, nil).Search(ctx, types.Query{}, Page{}); err == nil {
+		t.Error("malformed response accepted")
+	}
+	if _, _, err := NewElasticsearch(srv.URL, "x", nil, nil).Search(ctx, types.Query{}, Page{Cursor: "!"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("want ErrInvalidCursor, got %v", err)
+	}
+	if _, _, err := NewElasticsearch(srv.URL, "x", nil, nil).Search(ctx, types.Query{"a": map[string]any{}}, Page{}); !errors.Is(err, elastic.ErrUnsupported) {
+		t.Errorf("want ErrUnsupported, got %v", err)
+	}
+	if err := NewElasticsearch(srv.URL, "missing", nil, nil).Ping(ctx); err == nil {
+		t.Error("Ping of a missing index succeeded")
+	}
+}
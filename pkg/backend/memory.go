@@ -0,0 +1,221 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"search-filter/pkg/elastic"
+	"search-filter/pkg/types"
+)
+
+// Memory — бэкенд поверх набора документов в памяти, для тестов и локальной разработки.
+// Семантика совпадает с компиляцией в Elasticsearch: ключи запроса сопоставляются
+// полям документа через elastic.Mapping.
+type Memory struct {
+	docs    []Document
+	mapping elastic.Mapping
+}
+
+func NewMemory(docs []Document, mapping elastic.Mapping) *Memory {
+	return &Memory{docs: docs, mapping: mapping}
+}
+
+// LoadMemory читает набор документов из JSON-файла с массивом объектов.
+func LoadMemory(path string, mapping elastic.Mapping) (*Memory, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read dataset: %w", err)
+	}
+	var docs []Document
+	if err := json.Unmarshal(b, &docs); err != nil {
+		return nil, fmt.Errorf("parse dataset %s: %w", path, err)
+	}
+	return NewMemory(docs, mapping), nil
+}
+
+func (m *Memory) Search(ctx context.Context, query types.Query, page Page) ([]Document, string, error) {
+	off, err := decodeOffset(page.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	// Запросы, которые не компилируются для Elasticsearch, отклоняются и здесь,
+	// чтобы фильтр не находил в памяти то, на что Elasticsearch ответит ошибкой.
+	if _, err := elastic.Compile(query, m.mapping); err != nil {
+		return nil, "", err
+	}
+
+	var matched []Document
+	for _, d := range m.docs {
+		ok, err := m.matches(d, query)
+		if err != nil {
+			return nil, "", err
+		}
+		if ok {
+			matched = append(matched, d)
+		}
+	}
+
+	if off >= len(matched) {
+		return []Document{}, "", nil
+	}
+	end := len(matched)
+	if page.Limit > 0 && off+page.Limit < end {
+		end = off + page.Limit
+	}
+	next := ""
+	if end < len(matched) {
+		next = encodeOffset(end)
+	}
+	return matched[off:end], next, nil
+}
+
+func (m *Memory) matches(d Document, q types.Query) (bool, error) {
+	for k, v := range q {
+		if v == nil {
+			continue
+		}
+		f, ok := m.mapping[k]
+		if !ok {
+			f = elastic.Field{Field: k, Kind: elastic.KindTerm}
+			if _, isList := v.([]any); isList {
+				f.Kind = elastic.KindTerms
+			}
+		}
+		got := d[f.Field]
+
+		switch f.Kind {
+		case elastic.KindTerm:
+			if !containsValue(got, v) {
+				return false, nil
+			}
+		case elastic.KindTerms:
+			list, ok := v.([]any)
+			if !ok {
+				list = []any{v}
+			}
+			if len(list) == 0 {
+				continue
+			}
+			found := false
+			for _, it := range list {
+				if containsValue(got, it) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false, nil
+			}
+		case elastic.KindMatch:
+			// Анализ нетекстовых и многозначных полей в Elasticsearch здесь не
+			// воспроизводится: такой запрос дал бы другие результаты.
+			if got == nil {
+				return false, nil
+			}
+			text, ok := got.(string)
+			if !ok {
+				return false, fmt.Errorf("%w: match on non-text field %q", elastic.ErrUnsupported, f.Field)
+			}
+			if !matchWords(text, fmt.Sprint(v)) {
+				return false, nil
+			}
+		case elastic.KindGTE, elastic.KindGT, elastic.KindLTE, elastic.KindLT:
+			c, ok := compare(got, v)
+			if !ok {
+				return false, nil
+			}
+			switch f.Kind {
+			case elastic.KindGTE:
+				ok = c >= 0
+			case elastic.KindGT:
+				ok = c > 0
+			case elastic.KindLTE:
+				ok = c <= 0
+			case elastic.KindLT:
+				ok = c < 0
+			}
+			if !ok {
+				return false, nil
+			}
+		default:
+			return false, fmt.Errorf("%w: kind %q", elastic.ErrUnsupported, f.Kind)
+		}
+	}
+	return true, nil
+}
+
+// containsValue сравнивает значение поля с v; для полей-массивов достаточно совпадения одного элемента.
+func containsValue(field, v any) bool {
+	if list, ok := field.([]any); ok {
+		for _, it := range list {
+			if c, ok := compare(it, v); ok && c == 0 {
+				return true
+			}
+		}
+		return false
+	}
+	c, ok := compare(field, v)
+	return ok && c == 0
+}
+
+// matchWords повторяет поведение match с оператором OR: достаточно одного общего слова.
+func matchWords(text, query string) bool {
+	words := map[string]struct{}{}
+	for _, w := range strings.Fields(strings.ToLower(text)) {
+		words[w] = struct{}{}
+	}
+	for _, w := range strings.Fields(strings.ToLower(query)) {
+		if _, ok := words[w]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func compare(a, b any) (int, bool) {
+	switch x := a.(type) {
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(x, y), true
+	case float64:
+		y, ok := toFloat(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	case bool:
+		y, ok := b.(bool)
+		if !ok || x != y {
+			return 1, ok
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
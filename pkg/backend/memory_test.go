@@ -0,0 +1,158 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"search-filter/pkg/elastic"
+	"search-filter/pkg/types"
+)
+
+func loadFixture(t *testing.T) *Memory {
+	t.Helper()
+	m, err := LoadMemory("testdata/documents.json", elastic.DefaultMapping())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func ids(docs []Document) []string {
+	res := make([]string, 0, len(docs))
+	for _, d := range docs {
+		res = append(res, d["_id"].(string))
+	}
+	return res
+}
+
+func TestMemorySearch(t *testing.T) {
+	m := loadFixture(t)
+
+	tests := []struct {
+		name  string
+		query types.Query
+		want  []string
+	}{
+		{name: "empty", query: types.Query{}, want: []string{"1", "2", "3", "4", "5"}},
+		{name: "null ignored", query: types.Query{"author": nil}, want: []string{"1", "2", "3", "4", "5"}},
+		{name: "term", query: types.Query{"author": "42"}, want: []string{"1", "3"}},
+		{name: "term on array field", query: types.Query{"tags": "rust"}, want: []string{"2"}},
+		{name: "terms any of", query: types.Query{"tags": []any{"rust", "sql"}}, want: []string{"2", "4"}},
+		{name: "empty terms ignored", query: types.Query{"tags": []any{}}, want: []string{"1", "2", "3", "4", "5"}},
+		{name: "match any word case-insensitive", query: types.Query{"text": "GO borrow"}, want: []string{"1", "2", "3"}},
+		{name: "date range", query: types.Query{"date_from": "2025-02-01", "date_to": "2025-03-01"}, want: []string{"2", "3", "4"}},
+		{name: "number compared with int", query: types.Query{"rating": 4.5}, want: []string{"1"}},
+		{name: "bool", query: types.Query{"draft": true}, want: []string{"3"}},
+		{name: "conditions are combined with AND", query: types.Query{"author": "42", "draft": false}, want: []string{"1"}},
+		{name: "missing field does not match", query: types.Query{"tags": "golang", "author": "13"}, want: nil},
+		{name: "type mismatch does not match", query: types.Query{"author": 42}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, next, err := m.Search(context.Background(), tt.query, Page{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(docs); !slices.Equal(got, tt.want) && !(len(got) == 0 && len(tt.want) == 0) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if next != "" {
+				t.Errorf("next = %q without limit", next)
+			}
+		})
+	}
+}
+
+// Формы запросов, которые Elasticsearch обрабатывает иначе, чем поиск в памяти,
+// отклоняются с elastic.ErrUnsupported, как и в elastic.Compile.
+func TestMemoryUnsupported(t *testing.T) {
+	m, err := LoadMemory("testdata/documents.json", elastic.DefaultMapping().Merge(elastic.Mapping{
+		"topic":  {Field: "tags", Kind: elastic.KindMatch},
+		"rating": {Field: "rating", Kind: elastic.KindMatch},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		query types.Query
+	}{
+		{name: "match on list field", query: types.Query{"topic": "golang"}},
+		{name: "match on number field", query: types.Query{"rating": "4.5"}},
+		{name: "match with list value", query: types.Query{"text": []any{"go"}}},
+		{name: "term with object value", query: types.Query{"author": map[string]any{"id": "42"}}},
+		{name: "terms with nested list", query: types.Query{"tags": []any{[]any{"golang"}}}},
+		{name: "range with list value", query: types.Query{"date_from": []any{"2025-01-01"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := m.Search(context.Background(), tt.query, Page{}); !errors.Is(err, elastic.ErrUnsupported) {
+				t.Errorf("want elastic.ErrUnsupported, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMemoryRangeKinds(t *testing.T) {
+	m := NewMemory([]Document{
+		{"_id": "a", "price": 1.0},
+		{"_id": "b", "price": 5.0},
+		{"_id": "c", "price": 10.0},
+	}, elastic.Mapping{
+		"min": {Field: "price", Kind: elastic.KindGT},
+		"max": {Field: "price", Kind: elastic.KindLT},
+	})
+	docs, _, err := m.Search(context.Background(), types.Query{"min": 1, "max": 10}, Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(docs); !slices.Equal(got, []string{"b"}) {
+		t.Errorf("got %v, want [b]", got)
+	}
+}
+
+func TestMemoryPaging(t *testing.T) {
+	m := loadFixture(t)
+	ctx := context.Background()
+
+	var (
+		got    []string
+		cursor string
+		pages  int
+	)
+	for {
+		docs, next, err := m.Search(ctx, types.Query{}, Page{Cursor: cursor, Limit: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, ids(docs)...)
+		pages++
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if !slices.Equal(got, []string{"1", "2", "3", "4", "5"}) || pages != 3 {
+		t.Errorf("got %v in %d pages", got, pages)
+	}
+
+	docs, next, err := m.Search(ctx, types.Query{}, Page{Cursor: encodeOffset(10), Limit: 2})
+	if err != nil || len(docs) != 0 || next != "" {
+		t.Errorf("past the end = %v %q %v", docs, next, err)
+	}
+
+	for _, c := range []string{"!!!", encodeOffset(-1), "YWJj"} {
+		if _, _, err := m.Search(ctx, types.Query{}, Page{Cursor: c}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("cursor %q: want ErrInvalidCursor, got %v", c, err)
+		}
+	}
+}
+
+func TestLoadMemoryErrors(t *testing.T) {
+	if _, err := LoadMemory("testdata/missing.json", nil); err == nil {
+		t.Error("missing file accepted")
+	}
+}
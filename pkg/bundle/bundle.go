@@ -0,0 +1,125 @@
+package bundle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"search-filter/pkg/types"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// Version — текущая версия формата выгрузки.
+const Version = 1
+
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+var (
+	ErrUnsupportedFormat  = errors.New("unsupported bundle format")
+	ErrUnsupportedVersion = errors.New("unsupported bundle version")
+)
+
+type Bundle struct {
+	Version    int       `json:"version"     yaml:"version"`
+	ExportedAt time.Time `json:"exported_at" yaml:"exported_at"`
+	Filters    []Filter  `json:"filters"     yaml:"filters"`
+}
+
+type Filter struct {
+	ID    uuid.UUID   `json:"id"    yaml:"id"`
+	Name  string      `json:"name"  yaml:"name"`
+	Query types.Query `json:"query" yaml:"query"`
+}
+
+// ContentType возвращает MIME-тип формата.
+func ContentType(format string) string {
+	if format == FormatYAML {
+		return "application/yaml"
+	}
+	return "application/json"
+}
+
+// FormatFromContentType определяет формат по заголовку Content-Type; по умолчанию JSON.
+func FormatFromContentType(ct string) string {
+	if strings.Contains(ct, "yaml") {
+		return FormatYAML
+	}
+	return FormatJSON
+}
+
+// FormatFromPath определяет формат по расширению файла; по умолчанию JSON.
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	}
+	return FormatJSON
+}
+
+func Encode(w io.Writer, b *Bundle, format string) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(b)
+	case FormatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(b); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	return fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+}
+
+func Decode(data []byte, format string) (*Bundle, error) {
+	var b Bundle
+	switch format {
+	case FormatJSON:
+		if err := json.Unmarshal(data, &b); err != nil {
+			return nil, fmt.Errorf("decode bundle: %w", err)
+		}
+	case FormatYAML:
+		if err := yaml.Unmarshal(data, &b); err != nil {
+			return nil, fmt.Errorf("decode bundle: %w", err)
+		}
+		// YAML даёт int и вложенные map[string]any иначе, чем JSON;
+		// приводим запросы к тому же виду, что хранится в БД.
+		for i := range b.Filters {
+			q, err := normalize(b.Filters[i].Query)
+			if err != nil {
+				return nil, fmt.Errorf("decode bundle: filter %d: %w", i, err)
+			}
+			b.Filters[i].Query = q
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+
+	if b.Version < 1 || b.Version > Version {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, b.Version)
+	}
+	return &b, nil
+}
+
+func normalize(q types.Query) (types.Query, error) {
+	raw, err := json.Marshal(q)
+	if err != nil {
+		return nil, err
+	}
+	var res types.Query
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
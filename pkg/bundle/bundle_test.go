@@ -0,0 +1,99 @@
+package bundle
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"search-filter/pkg/types"
+
+	"github.com/google/uuid"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	src := &Bundle{
+		Version:    Version,
+		ExportedAt: time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC),
+		Filters: []Filter{{
+			ID:   uuid.MustParse("6f1c4b8e-0000-4000-8000-000000000001"),
+			Name: "Go",
+			Query: types.Query{
+				"tags":  []any{"golang"},
+				"limit": 10.0,
+				"range": map[string]any{"date": map[string]any{"gte": "{{today-7d}}"}},
+			},
+		}},
+	}
+
+	for _, format := range []string{FormatJSON, FormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Encode(&buf, src, format); err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			got, err := Decode(buf.Bytes(), format)
+			if err != nil {
+				t.Fatalf("Decode: %v\n%s", err, buf.String())
+			}
+			// YAML-числа и вложенные объекты приводятся к виду JSON.
+			if !reflect.DeepEqual(got, src) {
+				t.Errorf("round trip:\n got %#v\nwant %#v", got, src)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		format string
+		want   error
+	}{
+		{"unsupported format", `{}`, "xml", ErrUnsupportedFormat},
+		{"missing version", `{"filters":[]}`, FormatJSON, ErrUnsupportedVersion},
+		{"future version", "version: 2\nfilters: []\n", FormatYAML, ErrUnsupportedVersion},
+		{"malformed json", `{"version":`, FormatJSON, nil},
+		{"malformed yaml", "version: [", FormatYAML, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode([]byte(tt.data), tt.format)
+			if err == nil {
+				t.Fatal("Decode() error = nil")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if err := Encode(&bytes.Buffer{}, &Bundle{}, "xml"); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Encode: err = %v, want ErrUnsupportedFormat", err)
+	}
+}
+
+func TestFormats(t *testing.T) {
+	cases := map[string]string{
+		"application/json":              FormatJSON,
+		"application/yaml":              FormatYAML,
+		"application/x-yaml; charset=x": FormatYAML,
+		"":                              FormatJSON,
+	}
+	for ct, want := range cases {
+		if got := FormatFromContentType(ct); got != want {
+			t.Errorf("FormatFromContentType(%q) = %s, want %s", ct, got, want)
+		}
+	}
+	for path, want := range map[string]string{"a.yaml": FormatYAML, "b.YML": FormatYAML, "c.json": FormatJSON, "d": FormatJSON} {
+		if got := FormatFromPath(path); got != want {
+			t.Errorf("FormatFromPath(%q) = %s, want %s", path, got, want)
+		}
+	}
+	if !strings.Contains(ContentType(FormatYAML), "yaml") || ContentType(FormatJSON) != "application/json" {
+		t.Error("ContentType mismatch")
+	}
+}
@@ -0,0 +1,187 @@
+// Package cache — read-through кэш фильтров и отрендеренных запросов поверх
+// подключаемого хранилища (LRU в памяти процесса или Redis-совместимый сервер).
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Store — хранилище значений кэша. Отсутствие ключа — не ошибка: Get возвращает false.
+// ttl == 0 означает хранение без срока.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// genKey хранит текущее поколение кэша. Все остальные ключи содержат поколение,
+// поэтому инвалидация — это запись нового поколения, без перебора ключей
+// (в Redis их нельзя дёшево перечислить).
+const genKey = "gen"
+
+// Cache кэширует JSON-значения в Store и ведёт статистику попаданий по виду ключа —
+// части до первого двоеточия ("filter", "apply").
+type Cache struct {
+	store  Store
+	prefix string
+	ttl    time.Duration
+
+	invalidations atomic.Uint64
+	errors        atomic.Uint64
+
+	mu    sync.Mutex
+	kinds map[string]*kindStats
+}
+
+type kindStats struct {
+	hits, misses uint64
+}
+
+func New(store Store, prefix string, ttl time.Duration) *Cache {
+	return &Cache{store: store, prefix: prefix, ttl: ttl, kinds: map[string]*kindStats{}}
+}
+
+// Generation — поколение кэша, в котором был выполнен Get. Значение, прочитанное
+// из источника после промаха, сохраняется через Set в том же поколении: если
+// между ними кэш инвалидирован, запись уходит в старое поколение и не видна.
+// Нулевое значение — поколение неизвестно (хранилище недоступно), Set ничего не пишет.
+type Generation struct {
+	id string
+}
+
+// Get читает значение key в v и возвращает поколение, в котором читал.
+// Ошибки хранилища считаются промахом.
+func (c *Cache) Get(ctx context.Context, key string, v any) (Generation, bool) {
+	gen, ok := c.generation(ctx)
+	if !ok {
+		c.record(key, false)
+		return Generation{}, false
+	}
+	b, found, err := c.store.Get(ctx, c.prefix+gen+":"+key)
+	if err != nil {
+		c.errors.Add(1)
+		found = false
+	}
+	if found {
+		if err := json.Unmarshal(b, v); err != nil {
+			c.errors.Add(1)
+			found = false
+		}
+	}
+	c.record(key, found)
+	return Generation{id: gen}, found
+}
+
+// Set сохраняет v под key в поколении gen, полученном от Get.
+func (c *Cache) Set(ctx context.Context, gen Generation, key string, v any) {
+	if gen.id == "" {
+		return
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		c.errors.Add(1)
+		return
+	}
+	if err := c.store.Set(ctx, c.prefix+gen.id+":"+key, b, c.ttl); err != nil {
+		c.errors.Add(1)
+	}
+}
+
+// Invalidate делает недоступными все ранее сохранённые значения.
+func (c *Cache) Invalidate(ctx context.Context) {
+	c.invalidations.Add(1)
+	if err := c.store.Set(ctx, c.prefix+genKey, []byte(uuid.NewString()), 0); err != nil {
+		c.errors.Add(1)
+		// Без нового поколения старые значения остались бы видны; удаление ключа
+		// поколения даёт тот же эффект при следующем чтении.
+		if err := c.store.Delete(ctx, c.prefix+genKey); err != nil {
+			c.errors.Add(1)
+		}
+	}
+}
+
+func (c *Cache) generation(ctx context.Context) (string, bool) {
+	b, found, err := c.store.Get(ctx, c.prefix+genKey)
+	if err != nil {
+		c.errors.Add(1)
+		return "", false
+	}
+	if found {
+		return string(b), true
+	}
+	gen := uuid.NewString()
+	if err := c.store.Set(ctx, c.prefix+genKey, []byte(gen), 0); err != nil {
+		c.errors.Add(1)
+		return "", false
+	}
+	return gen, true
+}
+
+func (c *Cache) record(key string, hit bool) {
+	kind, _, _ := strings.Cut(key, ":")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.kinds[kind]
+	if !ok {
+		s = &kindStats{}
+		c.kinds[kind] = s
+	}
+	if hit {
+		s.hits++
+	} else {
+		s.misses++
+	}
+}
+
+type KindStats struct {
+	Hits     uint64  `json:"hits"`
+	Misses   uint64  `json:"misses"`
+	HitRatio float64 `json:"hit_ratio"`
+}
+
+type Stats struct {
+	Kinds         map[string]KindStats `json:"kinds"`
+	Invalidations uint64               `json:"invalidations"`
+	Errors        uint64               `json:"errors"`
+	// Entries — число записей для хранилищ, которые его сообщают (LRU), иначе -1.
+	Entries int `json:"entries"`
+}
+
+func (c *Cache) Stats() Stats {
+	st := Stats{
+		Kinds:         map[string]KindStats{},
+		Invalidations: c.invalidations.Load(),
+		Errors:        c.errors.Load(),
+		Entries:       -1,
+	}
+	if l, ok := c.store.(interface{ Len() int }); ok {
+		st.Entries = l.Len()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for kind, s := range c.kinds {
+		ks := KindStats{Hits: s.hits, Misses: s.misses}
+		if total := s.hits + s.misses; total > 0 {
+			ks.HitRatio = float64(s.hits) / float64(total)
+		}
+		st.Kinds[kind] = ks
+	}
+	return st
+}
+
+// Ping проверяет доступность хранилища, если оно внешнее (Redis); для LRU всегда nil.
+func (c *Cache) Ping(ctx context.Context) error {
+	if p, ok := c.store.(interface{ Ping(context.Context) error }); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
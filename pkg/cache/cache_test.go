@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	l := NewLRU(2)
+
+	_ = l.Set(ctx, "a", []byte("1"), 0)
+	_ = l.Set(ctx, "b", []byte("2"), 0)
+	if _, ok, _ := l.Get(ctx, "a"); !ok {
+		t.Fatal("a missing")
+	}
+	_ = l.Set(ctx, "c", []byte("3"), 0)
+
+	if _, ok, _ := l.Get(ctx, "b"); ok {
+		t.Error("b not evicted")
+	}
+	for _, k := range []string{"a", "c"} {
+		if _, ok, _ := l.Get(ctx, k); !ok {
+			t.Errorf("%s evicted", k)
+		}
+	}
+	if l.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", l.Len())
+	}
+}
+
+func TestLRUExpires(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLRU(10)
+	l.now = func() time.Time { return now }
+
+	_ = l.Set(ctx, "a", []byte("1"), time.Minute)
+	_ = l.Set(ctx, "forever", []byte("1"), 0)
+
+	now = now.Add(59 * time.Second)
+	if _, ok, _ := l.Get(ctx, "a"); !ok {
+		t.Fatal("a expired early")
+	}
+	now = now.Add(time.Second)
+	if _, ok, _ := l.Get(ctx, "a"); ok {
+		t.Error("a not expired")
+	}
+	if _, ok, _ := l.Get(ctx, "forever"); !ok {
+		t.Error("entry without ttl expired")
+	}
+}
+
+func TestCacheInvalidate(t *testing.T) {
+	ctx := context.Background()
+	c := New(NewLRU(10), "t:", time.Minute)
+
+	var v string
+	gen, ok := c.Get(ctx, "filter:1", &v)
+	if ok {
+		t.Fatal("hit on empty cache")
+	}
+	c.Set(ctx, gen, "filter:1", "one")
+	c.Set(ctx, gen, "apply:1", "rendered")
+	if _, ok := c.Get(ctx, "filter:1", &v); !ok || v != "one" {
+		t.Fatalf("Get = %q, want one", v)
+	}
+
+	c.Invalidate(ctx)
+	if _, ok := c.Get(ctx, "filter:1", &v); ok {
+		t.Error("filter hit after invalidate")
+	}
+	if _, ok := c.Get(ctx, "apply:1", &v); ok {
+		t.Error("apply hit after invalidate")
+	}
+
+	st := c.Stats()
+	if f := st.Kinds["filter"]; f.Hits != 1 || f.Misses != 2 {
+		t.Errorf("filter stats = %+v", f)
+	}
+	if a := st.Kinds["apply"]; a.Hits != 0 || a.Misses != 1 || a.HitRatio != 0 {
+		t.Errorf("apply stats = %+v", a)
+	}
+	if st.Invalidations != 1 {
+		t.Errorf("invalidations = %d, want 1", st.Invalidations)
+	}
+}
+
+func TestCacheSharedStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewLRU(10)
+	a := New(store, "t:", time.Minute)
+	b := New(store, "t:", time.Minute)
+
+	var v string
+	gen, _ := a.Get(ctx, "filter:1", &v)
+	a.Set(ctx, gen, "filter:1", "one")
+	if _, ok := b.Get(ctx, "filter:1", &v); !ok {
+		t.Fatal("value not shared through store")
+	}
+	b.Invalidate(ctx)
+	if _, ok := a.Get(ctx, "filter:1", &v); ok {
+		t.Error("invalidation not shared through store")
+	}
+}
+
+// Значение, прочитанное из источника до инвалидации, не должно попасть в новое
+// поколение, даже если запись в кэш выполняется после неё.
+func TestCacheSetAfterInvalidate(t *testing.T) {
+	ctx := context.Background()
+	c := New(NewLRU(10), "t:", time.Minute)
+
+	var v string
+	gen, ok := c.Get(ctx, "filter:1", &v)
+	if ok {
+		t.Fatal("hit on empty cache")
+	}
+	c.Invalidate(ctx)
+	c.Set(ctx, gen, "filter:1", "stale")
+	if _, ok := c.Get(ctx, "filter:1", &v); ok {
+		t.Errorf("stale value %q visible after invalidate", v)
+	}
+
+	c.Set(ctx, Generation{}, "filter:1", "unknown")
+	if _, ok := c.Get(ctx, "filter:1", &v); ok {
+		t.Error("Set with zero generation stored a value")
+	}
+}
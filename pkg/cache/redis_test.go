@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TestRedis запускается против Redis-совместимого сервера из SEARCHFILTER_TEST_REDIS_ADDR.
+func TestRedis(t *testing.T) {
+	addr := os.Getenv("SEARCHFILTER_TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("SEARCHFILTER_TEST_REDIS_ADDR is not set")
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() { client.Close() })
+
+	ctx := context.Background()
+	prefix := "search-filter-test:" + time.Now().Format(time.RFC3339Nano) + ":"
+	c := New(NewRedis(client), prefix, time.Minute)
+
+	var v string
+	gen, _ := c.Get(ctx, "filter:1", &v)
+	c.Set(ctx, gen, "filter:1", "one")
+	if _, ok := c.Get(ctx, "filter:1", &v); !ok || v != "one" {
+		t.Fatalf("Get = %q, want one", v)
+	}
+	c.Invalidate(ctx)
+	if _, ok := c.Get(ctx, "filter:1", &v); ok {
+		t.Error("hit after invalidate")
+	}
+	if st := c.Stats(); st.Errors != 0 {
+		t.Errorf("errors = %d", st.Errors)
+	}
+}
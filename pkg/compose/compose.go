@@ -0,0 +1,276 @@
+package compose
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"search-filter/pkg/types"
+
+	"github.com/google/uuid"
+)
+
+const (
+	KeyRef   = "$ref"
+	KeyAllOf = "all_of"
+
+	DefaultMaxDepth = 8
+)
+
+var (
+	ErrInvalidRef = errors.New("invalid filter reference")
+	ErrCycle      = errors.New("filter reference cycle")
+	ErrDepth      = errors.New("filter reference depth limit exceeded")
+)
+
+// Lookup возвращает сохранённый запрос фильтра по ID.
+type Lookup func(ctx context.Context, id uuid.UUID) (types.Query, error)
+
+// Refs возвращает ID фильтров, на которые запрос ссылается напрямую:
+// {"$ref": id} на верхнем уровне и элементы {"all_of": [...]}, включая вложенные.
+func Refs(q types.Query) ([]uuid.UUID, error) {
+	seen := map[uuid.UUID]struct{}{}
+	if err := collectRefs(q, seen); err != nil {
+		return nil, err
+	}
+	res := make([]uuid.UUID, 0, len(seen))
+	for id := range seen {
+		res = append(res, id)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].String() < res[j].String() })
+	return res, nil
+}
+
+func collectRefs(q map[string]any, seen map[uuid.UUID]struct{}) error {
+	if raw, ok := q[KeyRef]; ok {
+		id, err := parseRef(raw)
+		if err != nil {
+			return err
+		}
+		seen[id] = struct{}{}
+	}
+	items, err := allOf(q)
+	if err != nil {
+		return err
+	}
+	for _, it := range items {
+		if err := collectRefs(it, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RewriteRefs возвращает копию запроса, в которой ссылки заменены по таблице ids.
+// Ссылки, отсутствующие в таблице, остаются без изменений.
+func RewriteRefs(q types.Query, ids map[uuid.UUID]uuid.UUID) (types.Query, error) {
+	res, err := rewrite(q, ids)
+	if err != nil {
+		return nil, err
+	}
+	return types.Query(res), nil
+}
+
+func rewrite(q map[string]any, ids map[uuid.UUID]uuid.UUID) (map[string]any, error) {
+	res := make(map[string]any, len(q))
+	for k, v := range q {
+		res[k] = v
+	}
+	if raw, ok := q[KeyRef]; ok {
+		id, err := parseRef(raw)
+		if err != nil {
+			return nil, err
+		}
+		if to, ok := ids[id]; ok {
+			res[KeyRef] = to.String()
+		}
+	}
+	items, err := allOf(q)
+	if err != nil {
+		return nil, err
+	}
+	if items != nil {
+		out := make([]any, 0, len(items))
+		for _, it := range items {
+			r, err := rewrite(it, ids)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, r)
+		}
+		res[KeyAllOf] = out
+	}
+	return res, nil
+}
+
+// Resolve раскрывает ссылки в запросе q фильтра root (uuid.Nil для ещё не созданного).
+// Запросы объединяются слева направо: сначала $ref, затем элементы all_of, затем
+// собственные ключи запроса. Скаляры перезаписываются, массивы объединяются без
+// повторов, объекты сливаются рекурсивно.
+func Resolve(ctx context.Context, root uuid.UUID, q types.Query, lookup Lookup, maxDepth int) (types.Query, error) {
+	r := resolver{lookup: lookup, maxDepth: maxDepth, cache: map[uuid.UUID]types.Query{}}
+	var stack []uuid.UUID
+	if root != uuid.Nil {
+		stack = append(stack, root)
+	}
+	res, err := r.resolve(ctx, q, stack, 0)
+	if err != nil {
+		return nil, err
+	}
+	return types.Query(res), nil
+}
+
+type resolver struct {
+	lookup   Lookup
+	maxDepth int
+	cache    map[uuid.UUID]types.Query
+}
+
+func (r *resolver) resolve(ctx context.Context, q map[string]any, stack []uuid.UUID, depth int) (map[string]any, error) {
+	if depth > r.maxDepth {
+		return nil, fmt.Errorf("%w (%d)", ErrDepth, r.maxDepth)
+	}
+
+	res := map[string]any{}
+	if raw, ok := q[KeyRef]; ok {
+		id, err := parseRef(raw)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range stack {
+			if s == id {
+				return nil, fmt.Errorf("%w: %s", ErrCycle, id)
+			}
+		}
+		ref, err := r.get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		resolved, err := r.resolve(ctx, ref, append(stack[:len(stack):len(stack)], id), depth+1)
+		if err != nil {
+			return nil, err
+		}
+		merge(res, resolved)
+	}
+
+	items, err := allOf(q)
+	if err != nil {
+		return nil, err
+	}
+	for _, it := range items {
+		resolved, err := r.resolve(ctx, it, stack, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		merge(res, resolved)
+	}
+
+	own := make(map[string]any, len(q))
+	for k, v := range q {
+		if k != KeyRef && k != KeyAllOf {
+			own[k] = v
+		}
+	}
+	merge(res, own)
+	return res, nil
+}
+
+func (r *resolver) get(ctx context.Context, id uuid.UUID) (types.Query, error) {
+	if q, ok := r.cache[id]; ok {
+		return q, nil
+	}
+	q, err := r.lookup(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", id, err)
+	}
+	r.cache[id] = q
+	return q, nil
+}
+
+func merge(dst, src map[string]any) {
+	for k, v := range src {
+		cur, ok := dst[k]
+		if !ok {
+			dst[k] = v
+			continue
+		}
+		switch c := cur.(type) {
+		case []any:
+			if l, ok := v.([]any); ok {
+				dst[k] = union(c, l)
+				continue
+			}
+		case map[string]any:
+			if m, ok := v.(map[string]any); ok {
+				n := make(map[string]any, len(c))
+				merge(n, c)
+				merge(n, m)
+				dst[k] = n
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
+
+func union(a, b []any) []any {
+	res := make([]any, 0, len(a)+len(b))
+	seen := map[string]struct{}{}
+	for _, l := range [][]any{a, b} {
+		for _, v := range l {
+			key, err := json.Marshal(v)
+			if err == nil {
+				if _, dup := seen[string(key)]; dup {
+					continue
+				}
+				seen[string(key)] = struct{}{}
+			}
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+func parseRef(raw any) (uuid.UUID, error) {
+	s, ok := raw.(string)
+	if !ok {
+		return uuid.Nil, fmt.Errorf("%w: %s must be a string", ErrInvalidRef, KeyRef)
+	}
+	id, err := uuid.Parse(s)
+	if err != nil || id == uuid.Nil {
+		return uuid.Nil, fmt.Errorf("%w: %q", ErrInvalidRef, s)
+	}
+	return id, nil
+}
+
+func allOf(q map[string]any) ([]map[string]any, error) {
+	raw, ok := q[KeyAllOf]
+	if !ok {
+		return nil, nil
+	}
+	list, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s must be an array", ErrInvalidRef, KeyAllOf)
+	}
+	res := make([]map[string]any, 0, len(list))
+	for _, it := range list {
+		m, ok := asMap(it)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s items must be objects", ErrInvalidRef, KeyAllOf)
+		}
+		res = append(res, m)
+	}
+	return res, nil
+}
+
+func asMap(v any) (map[string]any, bool) {
+	switch m := v.(type) {
+	case map[string]any:
+		return m, true
+	case types.Query:
+		return m, true
+	}
+	return nil, false
+}
@@ -0,0 +1,259 @@
+package compose
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"search-filter/pkg/types"
+
+	"github.com/google/uuid"
+)
+
+var (
+	idA = uuid.MustParse("00000000-0000-4000-8000-00000000000a")
+	idB = uuid.MustParse("00000000-0000-4000-8000-00000000000b")
+	idC = uuid.MustParse("00000000-0000-4000-8000-00000000000c")
+)
+
+var errMissing = errors.New("missing")
+
+func lookupIn(store map[uuid.UUID]types.Query) Lookup {
+	return func(_ context.Context, id uuid.UUID) (types.Query, error) {
+		q, ok := store[id]
+		if !ok {
+			return nil, errMissing
+		}
+		return q, nil
+	}
+}
+
+func ref(id uuid.UUID) map[string]any {
+	return map[string]any{KeyRef: id.String()}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		root    uuid.UUID
+		q       types.Query
+		store   map[uuid.UUID]types.Query
+		depth   int
+		want    string
+		wantErr error
+	}{
+		{
+			name: "no refs",
+			q:    types.Query{"tags": []any{"go"}},
+			want: `{"tags":["go"]}`,
+		},
+		{
+			name:  "ref with own keys overriding scalars",
+			q:     types.Query{KeyRef: idA.String(), "author": "7"},
+			store: map[uuid.UUID]types.Query{idA: {"author": "42", "lang": "en"}},
+			want:  `{"author":"7","lang":"en"}`,
+		},
+		{
+			name: "all_of merges arrays without duplicates and objects recursively",
+			q:    types.Query{KeyAllOf: []any{ref(idA), ref(idB)}, "tags": []any{"c"}},
+			store: map[uuid.UUID]types.Query{
+				idA: {"tags": []any{"a", "b"}, "range": map[string]any{"date": map[string]any{"gte": "2025-01-01"}}},
+				idB: {"tags": []any{"b"}, "range": map[string]any{"date": map[string]any{"lte": "2025-02-01"}}},
+			},
+			want: `{"range":{"date":{"gte":"2025-01-01","lte":"2025-02-01"}},"tags":["a","b","c"]}`,
+		},
+		{
+			name: "ref before all_of before own keys",
+			q:    types.Query{KeyRef: idA.String(), KeyAllOf: []any{map[string]any{"x": "all_of"}}, "y": "own"},
+			store: map[uuid.UUID]types.Query{
+				idA: {"x": "ref", "y": "ref"},
+			},
+			want: `{"x":"all_of","y":"own"}`,
+		},
+		{
+			name:  "type conflict: later value wins",
+			q:     types.Query{KeyRef: idA.String(), "tags": "scalar"},
+			store: map[uuid.UUID]types.Query{idA: {"tags": []any{"a"}}},
+			want:  `{"tags":"scalar"}`,
+		},
+		{
+			name: "transitive refs",
+			q:    types.Query(ref(idA)),
+			store: map[uuid.UUID]types.Query{
+				idA: {KeyRef: idB.String(), "a": 1},
+				idB: {"b": 2},
+			},
+			want: `{"a":1,"b":2}`,
+		},
+		{
+			name: "diamond is not a cycle",
+			q:    types.Query{KeyAllOf: []any{ref(idA), ref(idB)}},
+			store: map[uuid.UUID]types.Query{
+				idA: {KeyRef: idC.String()},
+				idB: {KeyRef: idC.String()},
+				idC: {"c": true},
+			},
+			want: `{"c":true}`,
+		},
+		{
+			name:    "dangling ref",
+			q:       types.Query(ref(idA)),
+			wantErr: errMissing,
+		},
+		{
+			name:    "self reference",
+			root:    idA,
+			q:       types.Query(ref(idA)),
+			wantErr: ErrCycle,
+		},
+		{
+			name: "indirect cycle",
+			root: idA,
+			q:    types.Query(ref(idB)),
+			store: map[uuid.UUID]types.Query{
+				idB: {KeyAllOf: []any{ref(idC)}},
+				idC: {KeyRef: idA.String()},
+				idA: {},
+			},
+			wantErr: ErrCycle,
+		},
+		{
+			name: "cycle not involving root",
+			q:    types.Query(ref(idA)),
+			store: map[uuid.UUID]types.Query{
+				idA: {KeyRef: idB.String()},
+				idB: {KeyRef: idA.String()},
+			},
+			wantErr: ErrCycle,
+		},
+		{
+			name: "depth limit",
+			q:    types.Query(ref(idA)),
+			store: map[uuid.UUID]types.Query{
+				idA: {KeyRef: idB.String()},
+				idB: {KeyRef: idC.String()},
+				idC: {},
+			},
+			depth:   2,
+			wantErr: ErrDepth,
+		},
+		{
+			name:    "nested all_of counts towards depth",
+			q:       types.Query{KeyAllOf: []any{map[string]any{KeyAllOf: []any{map[string]any{"x": 1}}}}},
+			depth:   1,
+			wantErr: ErrDepth,
+		},
+		{
+			name:    "ref is not a string",
+			q:       types.Query{KeyRef: 42},
+			wantErr: ErrInvalidRef,
+		},
+		{
+			name:    "ref is not a uuid",
+			q:       types.Query{KeyRef: "filter-1"},
+			wantErr: ErrInvalidRef,
+		},
+		{
+			name:    "nil uuid ref",
+			q:       types.Query{KeyRef: uuid.Nil.String()},
+			wantErr: ErrInvalidRef,
+		},
+		{
+			name:    "all_of is not an array",
+			q:       types.Query{KeyAllOf: map[string]any{}},
+			wantErr: ErrInvalidRef,
+		},
+		{
+			name:    "all_of item is not an object",
+			q:       types.Query{KeyAllOf: []any{"x"}},
+			wantErr: ErrInvalidRef,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			depth := tt.depth
+			if depth == 0 {
+				depth = DefaultMaxDepth
+			}
+			got, err := Resolve(context.Background(), tt.root, tt.q, lookupIn(tt.store), depth)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("want %v, got %v (%v)", tt.wantErr, err, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			b, _ := json.Marshal(got)
+			if string(b) != tt.want {
+				t.Errorf("got  %s\nwant %s", b, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveDoesNotModifyStore(t *testing.T) {
+	store := map[uuid.UUID]types.Query{idA: {"tags": []any{"a"}, "range": map[string]any{"gte": 1}}}
+	_, err := Resolve(context.Background(), uuid.Nil,
+		types.Query{KeyRef: idA.String(), "tags": []any{"b"}, "range": map[string]any{"lte": 2}}, lookupIn(store), DefaultMaxDepth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := json.Marshal(store[idA])
+	if string(b) != `{"range":{"gte":1},"tags":["a"]}` {
+		t.Errorf("stored query modified: %s", b)
+	}
+}
+
+func TestRefs(t *testing.T) {
+	q := types.Query{
+		KeyRef: idC.String(),
+		KeyAllOf: []any{
+			ref(idA),
+			map[string]any{KeyAllOf: []any{ref(idB), ref(idA)}},
+		},
+	}
+	got, err := Refs(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint([]uuid.UUID{idA, idB, idC}) {
+		t.Errorf("Refs = %v", got)
+	}
+
+	got, err = Refs(types.Query{"tags": []any{"go"}})
+	if err != nil || len(got) != 0 {
+		t.Errorf("Refs without refs = %v, %v", got, err)
+	}
+	if _, err := Refs(types.Query{KeyAllOf: []any{map[string]any{KeyRef: "bad"}}}); !errors.Is(err, ErrInvalidRef) {
+		t.Errorf("want ErrInvalidRef, got %v", err)
+	}
+}
+
+func TestRewriteRefs(t *testing.T) {
+	q := types.Query{
+		KeyRef:   idA.String(),
+		KeyAllOf: []any{ref(idB), map[string]any{KeyAllOf: []any{ref(idC)}}},
+		"tags":   []any{"go"},
+	}
+	got, err := RewriteRefs(q, map[uuid.UUID]uuid.UUID{idA: idB, idC: idA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := json.Marshal(got)
+	want := fmt.Sprintf(`{"$ref":%q,"all_of":[{"$ref":%q},{"all_of":[{"$ref":%q}]}],"tags":["go"]}`, idB, idB, idA)
+	if string(b) != want {
+		t.Errorf("got  %s\nwant %s", b, want)
+	}
+	if q[KeyRef] != idA.String() {
+		t.Error("RewriteRefs modified the input")
+	}
+
+	if _, err := RewriteRefs(types.Query{KeyRef: 1}, nil); !errors.Is(err, ErrInvalidRef) {
+		t.Errorf("want ErrInvalidRef, got %v", err)
+	}
+}
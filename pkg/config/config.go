@@ -1,70 +1,339 @@
 package config
 
 import (
-	"log"
+	"fmt"
 	"net/url"
-	"os"
+	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/spf13/viper"
+	"search-filter/pkg/elastic"
+	"search-filter/pkg/logging"
+	"search-filter/pkg/models"
+	"search-filter/pkg/tracing"
+
+	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Timezone         string `mapstructure:"timezone"`
+	Timezone string `mapstructure:"timezone"`
+
+	LogLevel  string `mapstructure:"log_level"`
+	LogFormat string `mapstructure:"log_format"`
+	// LogSQL включает журнал SQL-запросов; пишется доля LogSQLSampleRate успешных запросов.
+	LogSQL           bool    `mapstructure:"log_sql"`
+	LogSQLSampleRate float64 `mapstructure:"log_sql_sample_rate"`
+
+	// TracingExporter — куда отправлять спаны OpenTelemetry: none, stdout или otlp.
+	TracingExporter     string  `mapstructure:"tracing_exporter"`
+	TracingOTLPEndpoint string  `mapstructure:"tracing_otlp_endpoint"`
+	TracingOTLPInsecure bool    `mapstructure:"tracing_otlp_insecure"`
+	TracingSampleRatio  float64 `mapstructure:"tracing_sample_ratio"`
+
+	HTTPAddr         string        `mapstructure:"http_addr"`
+	HTTPUnixSocket   string        `mapstructure:"http_unix_socket"`
+	HTTPReadTimeout  time.Duration `mapstructure:"http_read_timeout"`
+	HTTPWriteTimeout time.Duration `mapstructure:"http_write_timeout"`
+	HTTPIdleTimeout  time.Duration `mapstructure:"http_idle_timeout"`
+	HTTPBodyLimit    int           `mapstructure:"http_body_limit"`
+	HTTPTLSCert      string        `mapstructure:"http_tls_cert"`
+	HTTPTLSKey       string        `mapstructure:"http_tls_key"`
+
+	// HealthCheckTimeout ограничивает каждую проверку зависимостей в /readyz.
+	HealthCheckTimeout time.Duration `mapstructure:"health_check_timeout"`
+	// ShutdownDelay — пауза между снятием готовности и остановкой приёма соединений,
+	// чтобы балансировщик успел исключить реплику.
+	ShutdownDelay time.Duration `mapstructure:"shutdown_delay"`
+	// ShutdownTimeout ограничивает ожидание обрабатываемых запросов и фоновых задач.
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+
+	StorageDriver string `mapstructure:"storage_driver"`
+	SQLitePath    string `mapstructure:"sqlite_path"`
+
 	PostgresHost     string `mapstructure:"postgres_host"`
 	PostgresPort     string `mapstructure:"postgres_port"`
 	PostgresDB       string `mapstructure:"postgres_db"`
 	PostgresUser     string `mapstructure:"postgres_user"`
 	PostgresPassword string `mapstructure:"postgres_password"`
+	// PostgresRawDSN целиком заменяет DSN, собранный из остальных postgres_* ключей.
+	PostgresRawDSN string `mapstructure:"postgres_dsn"`
+
+	PostgresSSLMode          string        `mapstructure:"postgres_sslmode"`
+	PostgresSSLRootCert      string        `mapstructure:"postgres_sslrootcert"`
+	PostgresSSLCert          string        `mapstructure:"postgres_sslcert"`
+	PostgresSSLKey           string        `mapstructure:"postgres_sslkey"`
+	PostgresApplicationName  string        `mapstructure:"postgres_application_name"`
+	PostgresConnectTimeout   time.Duration `mapstructure:"postgres_connect_timeout"`
+	PostgresStatementTimeout time.Duration `mapstructure:"postgres_statement_timeout"`
+
+	PostgresMaxOpenConns    int           `mapstructure:"postgres_max_open_conns"`
+	PostgresMaxIdleConns    int           `mapstructure:"postgres_max_idle_conns"`
+	PostgresConnMaxLifetime time.Duration `mapstructure:"postgres_conn_max_lifetime"`
+	PostgresConnMaxIdleTime time.Duration `mapstructure:"postgres_conn_max_idle_time"`
+
+	ElasticsearchFields elastic.Mapping `mapstructure:"elasticsearch_fields"`
+	ElasticsearchURL    string          `mapstructure:"elasticsearch_url"`
+	ElasticsearchIndex  string          `mapstructure:"elasticsearch_index"`
+
+	SearchBackend        string `mapstructure:"search_backend"`
+	SearchBackendDataset string `mapstructure:"search_backend_dataset"`
+
+	AutoMigrate bool `mapstructure:"auto_migrate"`
+
+	CacheDriver        string        `mapstructure:"cache_driver"`
+	CacheSize          int           `mapstructure:"cache_size"`
+	CacheTTL           time.Duration `mapstructure:"cache_ttl"`
+	CacheRedisAddr     string        `mapstructure:"cache_redis_addr"`
+	CacheRedisPassword string        `mapstructure:"cache_redis_password"`
+	CacheRedisDB       int           `mapstructure:"cache_redis_db"`
+
+	Webhooks           []Webhook     `mapstructure:"webhooks"`
+	WebhookMaxAttempts int           `mapstructure:"webhook_max_attempts"`
+	WebhookTimeout     time.Duration `mapstructure:"webhook_timeout"`
+
+	VaultAddr              string        `mapstructure:"vault_addr"`
+	VaultToken             string        `mapstructure:"vault_token"`
+	VaultNamespace         string        `mapstructure:"vault_namespace"`
+	SecretsRefreshInterval time.Duration `mapstructure:"secrets_refresh_interval"`
+
+	// secretRefs — исходные ссылки секретов (ключ → "vault:…"), по ним Refreshed
+	// перечитывает значения.
+	secretRefs map[string]string
+	providers  map[string]SecretProvider
 }
 
+// Webhook — получатель событий жизненного цикла фильтров. Пустой Events — все события.
+type Webhook struct {
+	URL    string   `mapstructure:"url"`
+	Secret string   `mapstructure:"secret"`
+	Events []string `mapstructure:"events"`
+}
+
+const (
+	StoragePostgres = "postgres"
+	StorageSQLite   = "sqlite"
+	StorageMemory   = "memory"
+)
+
+const (
+	CacheNone   = ""
+	CacheMemory = "memory"
+	CacheRedis  = "redis"
+)
+
+const (
+	SearchBackendNone          = ""
+	SearchBackendMemory        = "memory"
+	SearchBackendElasticsearch = "elasticsearch"
+)
+
+// ElasticsearchMapping возвращает сопоставление по умолчанию,
+// дополненное полями из конфигурации.
+func (c Config) ElasticsearchMapping() elastic.Mapping {
+	return elastic.DefaultMapping().Merge(c.ElasticsearchFields)
+}
+
+// PostgresDSN — строка подключения для запросов сервиса, со statement_timeout.
 func (c Config) PostgresDSN() string {
+	return c.postgresDSN(true)
+}
+
+// PostgresAdminDSN — строка подключения для миграций и LISTEN: без statement_timeout,
+// который прервал бы долгую миграцию. postgres_dsn возвращается как есть.
+func (c Config) PostgresAdminDSN() string {
+	return c.postgresDSN(false)
+}
+
+func (c Config) postgresDSN(withStatementTimeout bool) string {
+	if c.PostgresRawDSN != "" {
+		return c.PostgresRawDSN
+	}
 	u := &url.URL{
 		Scheme: "postgres",
 		User:   url.UserPassword(c.PostgresUser, c.PostgresPassword),
 		Host:   c.PostgresHost + ":" + c.PostgresPort,
 		Path:   "/" + c.PostgresDB,
 	}
-	q := url.Values{"sslmode": []string{"disable"}}
+	q := url.Values{}
+	q.Set("sslmode", c.PostgresSSLMode)
+	if c.PostgresSSLRootCert != "" {
+		q.Set("sslrootcert", c.PostgresSSLRootCert)
+	}
+	if c.PostgresSSLCert != "" {
+		q.Set("sslcert", c.PostgresSSLCert)
+	}
+	if c.PostgresSSLKey != "" {
+		q.Set("sslkey", c.PostgresSSLKey)
+	}
+	if c.PostgresApplicationName != "" {
+		q.Set("application_name", c.PostgresApplicationName)
+	}
+	if c.PostgresConnectTimeout > 0 {
+		// connect_timeout — целые секунды; округление вверх, иначе 500ms стало бы
+		// нулём, то есть отсутствием ограничения.
+		secs := (c.PostgresConnectTimeout + time.Second - 1) / time.Second
+		q.Set("connect_timeout", strconv.FormatInt(int64(secs), 10))
+	}
+	// Неизвестные драйверу параметры lib/pq и pgx передают серверу как параметры сессии.
+	if withStatementTimeout && c.PostgresStatementTimeout > 0 {
+		q.Set("statement_timeout", strconv.FormatInt(c.PostgresStatementTimeout.Milliseconds(), 10))
+	}
 	u.RawQuery = q.Encode()
 	return u.String()
 }
 
-func MustLoad() *Config {
-	v := viper.New()
-	v.SetConfigType("yaml")
+// ValidationError перечисляет все отсутствующие и некорректные ключи разом,
+// чтобы не исправлять конфигурацию по одной ошибке за запуск.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "config: missing/invalid keys:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+func validate(cfg *Config) error {
+	var missing []string
 
-	cf := os.Getenv("CONFIG_FILE")
-	if cf == "" {
-		log.Fatal("CONFIG_FILE is required")
+	if cfg.Timezone == "" {
+		missing = append(missing, "timezone")
+	} else if _, err := time.LoadLocation(cfg.Timezone); err != nil {
+		missing = append(missing, fmt.Sprintf("timezone: %v", err))
+	}
+	switch cfg.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		missing = append(missing, "log_level (debug|info|warn|error)")
+	}
+	switch cfg.LogFormat {
+	case logging.FormatJSON, logging.FormatText:
+	default:
+		missing = append(missing, "log_format (json|text)")
+	}
+	if cfg.LogSQLSampleRate < 0 || cfg.LogSQLSampleRate > 1 {
+		missing = append(missing, "log_sql_sample_rate must be between 0 and 1")
+	}
+	switch cfg.TracingExporter {
+	case tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP:
+	default:
+		missing = append(missing, "tracing_exporter (none|stdout|otlp)")
+	}
+	if cfg.TracingSampleRatio < 0 || cfg.TracingSampleRatio > 1 {
+		missing = append(missing, "tracing_sample_ratio must be between 0 and 1")
+	}
+	if cfg.HTTPAddr == "" && cfg.HTTPUnixSocket == "" {
+		missing = append(missing, "http_addr or http_unix_socket")
+	}
+	if cfg.HTTPReadTimeout < 0 || cfg.HTTPWriteTimeout < 0 || cfg.HTTPIdleTimeout < 0 || cfg.HTTPBodyLimit <= 0 {
+		missing = append(missing, "http timeouts and http_body_limit must be positive")
+	}
+	if cfg.HealthCheckTimeout <= 0 {
+		missing = append(missing, "health_check_timeout must be positive")
+	}
+	if cfg.ShutdownDelay < 0 || cfg.ShutdownTimeout <= 0 {
+		missing = append(missing, "shutdown_delay and shutdown_timeout must be positive")
+	}
+	if (cfg.HTTPTLSCert == "") != (cfg.HTTPTLSKey == "") {
+		missing = append(missing, "http_tls_cert and http_tls_key must be set together")
 	}
-	v.SetConfigFile(cf)
 
-	_ = v.BindEnv("postgres_user", "POSTGRES_USER")
-	_ = v.BindEnv("postgres_password", "POSTGRES_PASSWORD")
+	switch cfg.StorageDriver {
+	case StoragePostgres:
+		missing = append(missing, validatePostgres(cfg)...)
+	case StorageSQLite:
+		if cfg.SQLitePath == "" {
+			missing = append(missing, "sqlite_path")
+		}
+	case StorageMemory:
+	default:
+		missing = append(missing, "storage_driver (postgres|sqlite|memory)")
+	}
 
-	if err := v.ReadInConfig(); err != nil {
-		log.Fatalf("config: cannot read %s: %v", cf, err)
+	if err := cfg.ElasticsearchFields.Validate(); err != nil {
+		missing = append(missing, err.Error())
+	}
+	switch cfg.SearchBackend {
+	case SearchBackendNone, SearchBackendMemory:
+	case SearchBackendElasticsearch:
+		if cfg.ElasticsearchURL == "" {
+			missing = append(missing, "elasticsearch_url")
+		}
+		if cfg.ElasticsearchIndex == "" {
+			missing = append(missing, "elasticsearch_index")
+		}
+	default:
+		missing = append(missing, "search_backend (memory|elasticsearch)")
 	}
 
-	var cfg Config
-	if err := v.UnmarshalExact(&cfg); err != nil {
-		log.Fatalf("config: unmarshal: %v", err)
+	switch cfg.CacheDriver {
+	case CacheNone, CacheMemory:
+	case CacheRedis:
+		if cfg.CacheRedisAddr == "" {
+			missing = append(missing, "cache_redis_addr")
+		}
+	default:
+		missing = append(missing, "cache_driver (memory|redis)")
+	}
+	if cfg.CacheSize <= 0 || cfg.CacheTTL < 0 {
+		missing = append(missing, "cache_size and cache_ttl must be positive")
 	}
-	validate(&cfg)
 
-	if _, err := time.LoadLocation(cfg.Timezone); err != nil {
-		log.Fatalf("config: invalid timezone %q: %v", cfg.Timezone, err)
+	if cfg.SecretsRefreshInterval < 0 {
+		missing = append(missing, "secrets_refresh_interval must be positive")
 	}
-	return &cfg
+	if cfg.WebhookMaxAttempts <= 0 || cfg.WebhookTimeout < 0 {
+		missing = append(missing, "webhook_max_attempts and webhook_timeout must be positive")
+	}
+	if len(cfg.Webhooks) > 0 && cfg.StorageDriver != StoragePostgres {
+		missing = append(missing, "webhooks require storage_driver: postgres")
+	}
+	for i, w := range cfg.Webhooks {
+		if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			missing = append(missing, fmt.Sprintf("webhooks[%d].url (http or https URL)", i))
+		}
+		if w.Secret == "" {
+			missing = append(missing, fmt.Sprintf("webhooks[%d].secret", i))
+		}
+		for _, ev := range w.Events {
+			switch ev {
+			case models.EventFilterCreated, models.EventFilterUpdated, models.EventFilterDeleted:
+			default:
+				missing = append(missing, fmt.Sprintf("webhooks[%d].events: unknown event %q", i, ev))
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		return &ValidationError{Problems: missing}
+	}
+	return nil
 }
 
-func validate(cfg *Config) {
+func validatePostgres(cfg *Config) []string {
 	var missing []string
 
-	if cfg.Timezone == "" {
-		missing = append(missing, "timezone")
+	if cfg.PostgresMaxOpenConns < 0 || cfg.PostgresMaxIdleConns < 0 ||
+		cfg.PostgresConnMaxLifetime < 0 || cfg.PostgresConnMaxIdleTime < 0 ||
+		cfg.PostgresConnectTimeout < 0 || cfg.PostgresStatementTimeout < 0 {
+		missing = append(missing, "postgres pool settings and timeouts must be positive")
+	}
+	if cfg.PostgresMaxOpenConns > 0 && cfg.PostgresMaxIdleConns > cfg.PostgresMaxOpenConns {
+		missing = append(missing, "postgres_max_idle_conns must not exceed postgres_max_open_conns")
+	}
+
+	if cfg.PostgresRawDSN != "" {
+		return missing
+	}
+
+	switch cfg.PostgresSSLMode {
+	case "disable", "require", "verify-ca", "verify-full":
+	default:
+		missing = append(missing, "postgres_sslmode (disable|require|verify-ca|verify-full)")
+	}
+	if (cfg.PostgresSSLCert == "") != (cfg.PostgresSSLKey == "") {
+		missing = append(missing, "postgres_sslcert and postgres_sslkey must be set together")
 	}
 	if cfg.PostgresHost == "" {
 		missing = append(missing, "postgres_host")
@@ -76,13 +345,66 @@ func validate(cfg *Config) {
 		missing = append(missing, "postgres_db")
 	}
 	if cfg.PostgresUser == "" {
-		missing = append(missing, "POSTGRES_USER env")
+		missing = append(missing, "postgres_user")
 	}
 	if cfg.PostgresPassword == "" {
-		missing = append(missing, "POSTGRES_PASSWORD env")
+		missing = append(missing, "postgres_password")
+	}
+	return missing
+}
+
+const redacted = "REDACTED"
+
+var dsnPassword = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// Redacted возвращает копию конфигурации с замаскированными паролями и секретами.
+func (c Config) Redacted() Config {
+	if c.PostgresPassword != "" {
+		c.PostgresPassword = redacted
+	}
+	if c.CacheRedisPassword != "" {
+		c.CacheRedisPassword = redacted
 	}
+	if c.VaultToken != "" {
+		c.VaultToken = redacted
+	}
+	if c.PostgresRawDSN != "" {
+		if u, err := url.Parse(c.PostgresRawDSN); err == nil && u.Scheme != "" {
+			if _, ok := u.User.Password(); ok {
+				u.User = url.UserPassword(u.User.Username(), redacted)
+			}
+			q := u.Query()
+			if q.Has("password") {
+				q.Set("password", redacted)
+				u.RawQuery = q.Encode()
+			}
+			c.PostgresRawDSN = u.String()
+		} else {
+			c.PostgresRawDSN = dsnPassword.ReplaceAllString(c.PostgresRawDSN, "${1}"+redacted)
+		}
+	}
+	webhooks := make([]Webhook, len(c.Webhooks))
+	for i, w := range c.Webhooks {
+		if w.Secret != "" {
+			w.Secret = redacted
+		}
+		webhooks[i] = w
+	}
+	c.Webhooks = webhooks
+	return c
+}
 
-	if len(missing) > 0 {
-		log.Fatalf("config: missing/invalid keys:\n  - %s", strings.Join(missing, "\n  - "))
+// YAML выводит конфигурацию в формате файла конфигурации, с длительностями вида "5s".
+func (c Config) YAML() ([]byte, error) {
+	out := map[string]any{}
+	rv := reflect.ValueOf(c)
+	for _, k := range keys() {
+		f := rv.Field(k.index)
+		if d, ok := f.Interface().(time.Duration); ok {
+			out[k.name] = d.String()
+			continue
+		}
+		out[k.name] = f.Interface()
 	}
+	return yaml.Marshal(out)
 }
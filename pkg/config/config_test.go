@@ -0,0 +1,212 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+func TestPostgresDSN(t *testing.T) {
+	base := Config{
+		PostgresHost:     "db",
+		PostgresPort:     "5432",
+		PostgresDB:       "filters",
+		PostgresUser:     "app",
+		PostgresPassword: "p@ss word",
+		PostgresSSLMode:  "disable",
+	}
+
+	full := base
+	full.PostgresSSLMode = "verify-full"
+	full.PostgresSSLRootCert = "/etc/ssl/ca.pem"
+	full.PostgresSSLCert = "/etc/ssl/client.pem"
+	full.PostgresSSLKey = "/etc/ssl/client.key"
+	full.PostgresApplicationName = "search-filter"
+	full.PostgresConnectTimeout = 5 * time.Second
+	full.PostgresStatementTimeout = 1500 * time.Millisecond
+
+	raw := full
+	raw.PostgresRawDSN = "host=other dbname=x"
+
+	subsecond := base
+	subsecond.PostgresConnectTimeout = 500 * time.Millisecond
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		dsn      string
+		adminDSN string
+	}{
+		{
+			name:     "minimal",
+			cfg:      base,
+			dsn:      "postgres://app:p%40ss%20word@db:5432/filters?sslmode=disable",
+			adminDSN: "postgres://app:p%40ss%20word@db:5432/filters?sslmode=disable",
+		},
+		{
+			name: "tls and session settings",
+			cfg:  full,
+			dsn: "postgres://app:p%40ss%20word@db:5432/filters?application_name=search-filter&connect_timeout=5" +
+				"&sslcert=%2Fetc%2Fssl%2Fclient.pem&sslkey=%2Fetc%2Fssl%2Fclient.key&sslmode=verify-full" +
+				"&sslrootcert=%2Fetc%2Fssl%2Fca.pem&statement_timeout=1500",
+			adminDSN: "postgres://app:p%40ss%20word@db:5432/filters?application_name=search-filter&connect_timeout=5" +
+				"&sslcert=%2Fetc%2Fssl%2Fclient.pem&sslkey=%2Fetc%2Fssl%2Fclient.key&sslmode=verify-full" +
+				"&sslrootcert=%2Fetc%2Fssl%2Fca.pem",
+		},
+		{
+			name:     "sub-second connect timeout rounds up",
+			cfg:      subsecond,
+			dsn:      "postgres://app:p%40ss%20word@db:5432/filters?connect_timeout=1&sslmode=disable",
+			adminDSN: "postgres://app:p%40ss%20word@db:5432/filters?connect_timeout=1&sslmode=disable",
+		},
+		{
+			name:     "raw override",
+			cfg:      raw,
+			dsn:      "host=other dbname=x",
+			adminDSN: "host=other dbname=x",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.PostgresDSN(); got != tt.dsn {
+				t.Errorf("PostgresDSN() = %s\n want %s", got, tt.dsn)
+			}
+			if got := tt.cfg.PostgresAdminDSN(); got != tt.adminDSN {
+				t.Errorf("PostgresAdminDSN() = %s\n want %s", got, tt.adminDSN)
+			}
+		})
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	yml := "timezone: Europe/Moscow\nstorage_driver: memory\ncache_size: 10\ncache_ttl: 1m\nhttp_addr: \":9000\"\n"
+	if err := os.WriteFile(file, []byte(yml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SEARCHFILTER_CACHE_SIZE", "20")
+	t.Setenv("SEARCHFILTER_CACHE_TTL", "2m")
+	t.Setenv("SEARCHFILTER_WEBHOOK_MAX_ATTEMPTS", "3")
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	RegisterFlags(fs)
+	if err := fs.Parse([]string{"--config", file, "--cache-ttl", "3m", "--elasticsearch-fields", `{"q":{"field":"title","kind":"match"}}`}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(WithFlags(fs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Timezone != "Europe/Moscow" || cfg.HTTPAddr != ":9000" {
+		t.Errorf("file values not applied: %+v", cfg)
+	}
+	if cfg.CacheSize != 20 || cfg.WebhookMaxAttempts != 3 {
+		t.Errorf("env must override file: cache_size=%d webhook_max_attempts=%d", cfg.CacheSize, cfg.WebhookMaxAttempts)
+	}
+	if cfg.CacheTTL != 3*time.Minute {
+		t.Errorf("flag must override env: cache_ttl=%s", cfg.CacheTTL)
+	}
+	if cfg.HTTPReadTimeout != 5*time.Second || cfg.PostgresMaxOpenConns != 20 {
+		t.Errorf("defaults not applied: %+v", cfg)
+	}
+	if f := cfg.ElasticsearchFields["q"]; f.Field != "title" {
+		t.Errorf("elasticsearch_fields from JSON flag = %+v", cfg.ElasticsearchFields)
+	}
+}
+
+func TestLoadWithoutFile(t *testing.T) {
+	t.Setenv("SEARCHFILTER_CONFIG_FILE", "")
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("SEARCHFILTER_STORAGE_DRIVER", "memory")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Timezone != "UTC" || cfg.StorageDriver != StorageMemory {
+		t.Errorf("got %+v", cfg)
+	}
+}
+
+func TestLoadValidationErrors(t *testing.T) {
+	t.Setenv("SEARCHFILTER_CONFIG_FILE", "")
+	t.Setenv("CONFIG_FILE", "")
+	for _, env := range []string{"POSTGRES_HOST", "POSTGRES_PORT", "POSTGRES_DB", "POSTGRES_USER", "POSTGRES_PASSWORD", "POSTGRES_DSN"} {
+		t.Setenv(env, "")
+	}
+	t.Setenv("SEARCHFILTER_TIMEZONE", "Mars/Olympus")
+	t.Setenv("SEARCHFILTER_CACHE_DRIVER", "memcached")
+	t.Setenv("SEARCHFILTER_TRACING_EXPORTER", "jaeger")
+
+	_, err := Load()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("want *ValidationError, got %v", err)
+	}
+	want := []string{"postgres_host", "postgres_user", "cache_driver (memory|redis)", "tracing_exporter (none|stdout|otlp)"}
+	for _, w := range want {
+		if !slices.Contains(verr.Problems, w) {
+			t.Errorf("problems %q do not contain %q", verr.Problems, w)
+		}
+	}
+	if !strings.HasPrefix(verr.Problems[0], "timezone: ") {
+		t.Errorf("first problem = %q, want invalid timezone", verr.Problems[0])
+	}
+}
+
+func TestLoadPostgresPool(t *testing.T) {
+	t.Setenv("SEARCHFILTER_CONFIG_FILE", "")
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("SEARCHFILTER_POSTGRES_HOST", "db")
+	t.Setenv("SEARCHFILTER_POSTGRES_PORT", "5432")
+	t.Setenv("SEARCHFILTER_POSTGRES_DB", "filters")
+	t.Setenv("SEARCHFILTER_POSTGRES_USER", "app")
+	t.Setenv("SEARCHFILTER_POSTGRES_PASSWORD", "secret")
+
+	t.Setenv("SEARCHFILTER_POSTGRES_MAX_OPEN_CONNS", "4")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("only max_open_conns below the default idle: %v", err)
+	}
+	if cfg.PostgresMaxOpenConns != 4 || cfg.PostgresMaxIdleConns != 4 {
+		t.Errorf("pool = %d open, %d idle, want 4 and 4", cfg.PostgresMaxOpenConns, cfg.PostgresMaxIdleConns)
+	}
+
+	t.Setenv("SEARCHFILTER_POSTGRES_MAX_IDLE_CONNS", "6")
+	_, err = Load()
+	var verr *ValidationError
+	if !errors.As(err, &verr) || !slices.Contains(verr.Problems, "postgres_max_idle_conns must not exceed postgres_max_open_conns") {
+		t.Errorf("explicit idle above open: got %v", err)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Config{
+		PostgresPassword:   "secret",
+		CacheRedisPassword: "secret",
+		PostgresRawDSN:     "host=db user=app password='s3 cret' sslmode=disable",
+		Webhooks:           []Webhook{{URL: "https://example.com/hook", Secret: "secret"}},
+	}
+	r := cfg.Redacted()
+	if r.PostgresPassword != redacted || r.CacheRedisPassword != redacted || r.Webhooks[0].Secret != redacted {
+		t.Errorf("secrets not redacted: %+v", r)
+	}
+	if r.PostgresRawDSN != "host=db user=app password=REDACTED sslmode=disable" {
+		t.Errorf("dsn = %s", r.PostgresRawDSN)
+	}
+	if cfg.Webhooks[0].Secret != "secret" {
+		t.Error("Redacted modified the original webhooks")
+	}
+
+	cfg.PostgresRawDSN = "postgres://app:s3cret@db:5432/filters?sslmode=disable"
+	if got := cfg.Redacted().PostgresRawDSN; got != "postgres://app:REDACTED@db:5432/filters?sslmode=disable" {
+		t.Errorf("url dsn = %s", got)
+	}
+}
@@ -0,0 +1,280 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"search-filter/pkg/logging"
+	"search-filter/pkg/tracing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix — префикс переменных окружения: ключ postgres_host задаётся
+// через SEARCHFILTER_POSTGRES_HOST.
+const EnvPrefix = "SEARCHFILTER_"
+
+// FlagConfigFile — флаг с путём к YAML-файлу конфигурации.
+const FlagConfigFile = "config"
+
+// defaults — значения ключей, не заданных ни файлом, ни окружением, ни флагами.
+var defaults = map[string]any{
+	"timezone": "UTC",
+
+	"log_level":           "info",
+	"log_format":          logging.FormatJSON,
+	"log_sql_sample_rate": 1.0,
+
+	"tracing_exporter":     tracing.ExporterNone,
+	"tracing_sample_ratio": 1.0,
+
+	"http_addr":          ":8080",
+	"http_read_timeout":  5 * time.Second,
+	"http_write_timeout": 10 * time.Second,
+	"http_idle_timeout":  60 * time.Second,
+	"http_body_limit":    4 << 20,
+
+	"health_check_timeout": 2 * time.Second,
+	"shutdown_timeout":     30 * time.Second,
+
+	"storage_driver": StoragePostgres,
+
+	"postgres_sslmode":            "disable",
+	"postgres_application_name":   "search-filter",
+	"postgres_max_open_conns":     20,
+	"postgres_max_idle_conns":     10,
+	"postgres_conn_max_lifetime":  30 * time.Minute,
+	"postgres_conn_max_idle_time": 5 * time.Minute,
+
+	"cache_size": 10000,
+	"cache_ttl":  5 * time.Minute,
+
+	"webhook_max_attempts": 10,
+	"webhook_timeout":      10 * time.Second,
+
+	"secrets_refresh_interval": 5 * time.Minute,
+}
+
+// key — ключ конфигурации, соответствующий полю Config.
+type key struct {
+	name  string
+	typ   reflect.Type
+	index int
+}
+
+// structured — ключ со списком или словарём; в окружении и флагах задаётся JSON.
+func (k key) structured() bool {
+	return k.typ.Kind() == reflect.Slice || k.typ.Kind() == reflect.Map
+}
+
+func (k key) env() []string {
+	names := []string{EnvPrefix + strings.ToUpper(k.name)}
+	// Имена, которые читались до появления префикса, остаются для совместимости.
+	if strings.HasPrefix(k.name, "postgres_") || strings.HasPrefix(k.name, "vault_") || k.name == "cache_redis_password" {
+		names = append(names, strings.ToUpper(k.name))
+	}
+	return names
+}
+
+func (k key) flag() string {
+	return strings.ReplaceAll(k.name, "_", "-")
+}
+
+func keys() []key {
+	t := reflect.TypeOf(Config{})
+	res := make([]key, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if name := f.Tag.Get("mapstructure"); name != "" {
+			res = append(res, key{name: name, typ: f.Type, index: i})
+		}
+	}
+	return res
+}
+
+// RegisterFlags добавляет в fs флаг --config и по флагу на каждый ключ:
+// postgres_host → --postgres-host.
+func RegisterFlags(fs *pflag.FlagSet) {
+	fs.String(FlagConfigFile, "", "YAML-файл конфигурации (по умолчанию $"+EnvPrefix+"CONFIG_FILE или $CONFIG_FILE)")
+	for _, k := range keys() {
+		usage := "ключ " + k.name
+		switch def := defaults[k.name]; {
+		case k.typ == reflect.TypeOf(time.Duration(0)):
+			d, _ := def.(time.Duration)
+			fs.Duration(k.flag(), d, usage)
+		case k.typ.Kind() == reflect.Int:
+			n, _ := def.(int)
+			fs.Int(k.flag(), n, usage)
+		case k.typ.Kind() == reflect.Float64:
+			f, _ := def.(float64)
+			fs.Float64(k.flag(), f, usage)
+		case k.typ.Kind() == reflect.Bool:
+			fs.Bool(k.flag(), false, usage)
+		case k.structured():
+			fs.String(k.flag(), "", usage+" в JSON")
+		default:
+			s, _ := def.(string)
+			fs.String(k.flag(), s, usage)
+		}
+	}
+}
+
+type loader struct {
+	file      string
+	flags     *pflag.FlagSet
+	providers map[string]SecretProvider
+}
+
+type LoadOption func(*loader)
+
+// WithFile читает конфигурацию из path вместо $SEARCHFILTER_CONFIG_FILE.
+func WithFile(path string) LoadOption {
+	return func(l *loader) { l.file = path }
+}
+
+// WithFlags применяет явно заданные флаги из fs, зарегистрированные RegisterFlags.
+func WithFlags(fs *pflag.FlagSet) LoadOption {
+	return func(l *loader) { l.flags = fs }
+}
+
+// WithSecretProvider подключает провайдер для ссылок "<scheme>:<ref>" в значениях секретов,
+// в том числе заменяя встроенные file и vault.
+func WithSecretProvider(scheme string, p SecretProvider) LoadOption {
+	return func(l *loader) {
+		if l.providers == nil {
+			l.providers = map[string]SecretProvider{}
+		}
+		l.providers[scheme] = p
+	}
+}
+
+// Load собирает конфигурацию: значения по умолчанию, затем необязательный
+// YAML-файл, переменные окружения и флаги — каждый следующий источник
+// переопределяет предыдущий. Затем ссылки на секреты заменяются значениями
+// из провайдеров. Ошибки проверки возвращаются как *ValidationError.
+func Load(opts ...LoadOption) (*Config, error) {
+	l := loader{file: os.Getenv(EnvPrefix + "CONFIG_FILE")}
+	if l.file == "" {
+		l.file = os.Getenv("CONFIG_FILE")
+	}
+	for _, opt := range opts {
+		opt(&l)
+	}
+	if l.flags != nil {
+		if f := l.flags.Lookup(FlagConfigFile); f != nil && f.Changed {
+			l.file = f.Value.String()
+		}
+	}
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	for k, d := range defaults {
+		v.SetDefault(k, d)
+	}
+	if l.file != "" {
+		v.SetConfigFile(l.file)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("config: cannot read %s: %w", l.file, err)
+		}
+	}
+
+	for _, k := range keys() {
+		if !k.structured() {
+			_ = v.BindEnv(append([]string{k.name}, k.env()...)...)
+			continue
+		}
+		for _, env := range k.env() {
+			if raw, ok := os.LookupEnv(env); ok {
+				if err := setJSON(v, k.name, raw); err != nil {
+					return nil, fmt.Errorf("config: %s: %w", env, err)
+				}
+				break
+			}
+		}
+	}
+
+	// Секрет из файла (…_FILE) используется, только если сама переменная не задана.
+	for _, name := range fileEnvKeys {
+		k := key{name: name}
+		if envSet(k.env()) {
+			continue
+		}
+		for _, env := range k.env() {
+			if path := os.Getenv(env + "_FILE"); path != "" {
+				v.Set(name, SchemeFile+":"+path)
+				break
+			}
+		}
+	}
+
+	if l.flags != nil {
+		for _, k := range keys() {
+			f := l.flags.Lookup(k.flag())
+			if f == nil || !f.Changed {
+				continue
+			}
+			if !k.structured() {
+				v.Set(k.name, f.Value.String())
+				continue
+			}
+			if err := setJSON(v, k.name, f.Value.String()); err != nil {
+				return nil, fmt.Errorf("config: --%s: %w", k.flag(), err)
+			}
+		}
+	}
+
+	var cfg Config
+	if err := v.UnmarshalExact(&cfg); err != nil {
+		return nil, fmt.Errorf("config: unmarshal: %w", err)
+	}
+	// Значение по умолчанию postgres_max_idle_conns не должно мешать уменьшить
+	// только postgres_max_open_conns; явно заданное по-прежнему проверяется.
+	if cfg.PostgresMaxOpenConns > 0 && cfg.PostgresMaxIdleConns > cfg.PostgresMaxOpenConns &&
+		!l.explicit(v, "postgres_max_idle_conns") {
+		cfg.PostgresMaxIdleConns = cfg.PostgresMaxOpenConns
+	}
+	if err := cfg.loadSecrets(l.providers); err != nil {
+		return nil, err
+	}
+	if err := validate(&cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// explicit сообщает, задан ли ключ файлом, окружением или флагом, а не взят из defaults.
+func (l loader) explicit(v *viper.Viper, name string) bool {
+	k := key{name: name}
+	if v.InConfig(name) || envSet(k.env()) {
+		return true
+	}
+	if l.flags != nil {
+		if f := l.flags.Lookup(k.flag()); f != nil && f.Changed {
+			return true
+		}
+	}
+	return false
+}
+
+func setJSON(v *viper.Viper, name, raw string) error {
+	var val any
+	if err := json.Unmarshal([]byte(raw), &val); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	v.Set(name, val)
+	return nil
+}
+
+func envSet(names []string) bool {
+	for _, name := range names {
+		if os.Getenv(name) != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SecretProvider возвращает секрет по ссылке. Ключи с секретами (postgres_password,
+// postgres_dsn, cache_redis_password, webhooks[].secret) вместо значения могут
+// содержать ссылку "<схема>:<ref>", например "vault:secret/data/search-filter#db_password";
+// Secret получает часть после двоеточия.
+type SecretProvider interface {
+	Secret(ctx context.Context, ref string) (string, error)
+}
+
+const (
+	SchemeFile  = "file"
+	SchemeVault = "vault"
+)
+
+// secretTimeout ограничивает чтение всех секретов при загрузке и обновлении.
+const secretTimeout = 10 * time.Second
+
+// FileProvider читает секрет из файла (Docker и Kubernetes secrets),
+// отбрасывая завершающий перевод строки.
+type FileProvider struct{}
+
+func (FileProvider) Secret(_ context.Context, path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// fileEnvKeys можно задать переменной окружения с суффиксом _FILE:
+// SEARCHFILTER_POSTGRES_PASSWORD_FILE=/run/secrets/db_password.
+var fileEnvKeys = []string{"postgres_password", "postgres_dsn", "cache_redis_password", "vault_token"}
+
+// secrets — значения, которые могут быть ссылками на секреты. vault_token сюда
+// не входит: им подключается сам Vault, поэтому он разрешается отдельно.
+func (c *Config) secrets() map[string]*string {
+	m := map[string]*string{
+		"postgres_password":    &c.PostgresPassword,
+		"postgres_dsn":         &c.PostgresRawDSN,
+		"cache_redis_password": &c.CacheRedisPassword,
+	}
+	for i := range c.Webhooks {
+		m[fmt.Sprintf("webhooks[%d].secret", i)] = &c.Webhooks[i].Secret
+	}
+	return m
+}
+
+// loadSecrets подключает провайдеры, запоминает ссылки и подставляет значения секретов.
+func (c *Config) loadSecrets(extra map[string]SecretProvider) error {
+	ctx, cancel := context.WithTimeout(context.Background(), secretTimeout)
+	defer cancel()
+
+	c.providers = map[string]SecretProvider{SchemeFile: FileProvider{}}
+	for scheme, p := range extra {
+		c.providers[scheme] = p
+	}
+
+	if scheme, ref, ok := strings.Cut(c.VaultToken, ":"); ok && scheme == SchemeFile {
+		token, err := c.providers[SchemeFile].Secret(ctx, ref)
+		if err != nil {
+			return fmt.Errorf("config: vault_token: %w", err)
+		}
+		c.VaultToken = token
+	}
+	if _, ok := c.providers[SchemeVault]; !ok && c.VaultAddr != "" {
+		c.providers[SchemeVault] = NewVault(c.VaultAddr, c.VaultToken, c.VaultNamespace)
+	}
+
+	c.secretRefs = map[string]string{}
+	for key, v := range c.secrets() {
+		scheme, _, ok := strings.Cut(*v, ":")
+		if !ok {
+			continue
+		}
+		if _, known := c.providers[scheme]; known {
+			c.secretRefs[key] = *v
+		} else if scheme == SchemeVault {
+			return fmt.Errorf("config: %s references vault, but vault_addr is not set", key)
+		}
+	}
+	return c.resolveSecrets(ctx)
+}
+
+func (c *Config) resolveSecrets(ctx context.Context) error {
+	secrets := c.secrets()
+	for key, raw := range c.secretRefs {
+		scheme, ref, _ := strings.Cut(raw, ":")
+		v, err := c.providers[scheme].Secret(ctx, ref)
+		if err != nil {
+			return fmt.Errorf("config: %s: %s secret: %w", key, scheme, err)
+		}
+		*secrets[key] = v
+	}
+	return nil
+}
+
+// HasSecretRefs сообщает, загружены ли секреты по ссылкам, которые имеет смысл
+// периодически перечитывать через Refreshed.
+func (c Config) HasSecretRefs() bool {
+	return len(c.secretRefs) > 0
+}
+
+// Refreshed перечитывает секреты по ссылкам и возвращает копию конфигурации
+// с новыми значениями; исходная конфигурация не меняется.
+func (c Config) Refreshed(ctx context.Context) (*Config, error) {
+	ctx, cancel := context.WithTimeout(ctx, secretTimeout)
+	defer cancel()
+
+	c.Webhooks = slices.Clone(c.Webhooks)
+	if err := c.resolveSecrets(ctx); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// vaultStub отдаёт секрет KV v2 по пути secret/data/search-filter.
+func vaultStub(t *testing.T, password *atomic.Value) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root-token" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/v1/secret/data/search-filter" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"data": map[string]any{
+				"data":     map[string]any{"db_password": password.Load()},
+				"metadata": map[string]any{"version": 1},
+			},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func clearConfigEnv(t *testing.T) {
+	t.Helper()
+	for _, env := range []string{
+		"SEARCHFILTER_CONFIG_FILE", "CONFIG_FILE",
+		"POSTGRES_PASSWORD", "SEARCHFILTER_POSTGRES_PASSWORD",
+		"POSTGRES_PASSWORD_FILE", "SEARCHFILTER_POSTGRES_PASSWORD_FILE",
+		"POSTGRES_DSN", "SEARCHFILTER_POSTGRES_DSN",
+		"VAULT_ADDR", "VAULT_TOKEN", "VAULT_NAMESPACE",
+	} {
+		t.Setenv(env, "")
+	}
+	t.Setenv("SEARCHFILTER_POSTGRES_HOST", "db")
+	t.Setenv("SEARCHFILTER_POSTGRES_PORT", "5432")
+	t.Setenv("SEARCHFILTER_POSTGRES_DB", "filters")
+	t.Setenv("SEARCHFILTER_POSTGRES_USER", "app")
+}
+
+func TestPasswordFile(t *testing.T) {
+	clearConfigEnv(t)
+	file := filepath.Join(t.TempDir(), "db_password")
+	if err := os.WriteFile(file, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("POSTGRES_PASSWORD_FILE", file)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PostgresPassword != "from-file" {
+		t.Fatalf("password = %q", cfg.PostgresPassword)
+	}
+
+	if err := os.WriteFile(file, []byte("rotated\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fresh, err := cfg.Refreshed(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh.PostgresPassword != "rotated" || cfg.PostgresPassword != "from-file" {
+		t.Errorf("after refresh: fresh=%q original=%q", fresh.PostgresPassword, cfg.PostgresPassword)
+	}
+
+	// Сама переменная важнее _FILE.
+	t.Setenv("SEARCHFILTER_POSTGRES_PASSWORD", "from-env")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PostgresPassword != "from-env" || cfg.HasSecretRefs() {
+		t.Errorf("password = %q, refs = %v", cfg.PostgresPassword, cfg.HasSecretRefs())
+	}
+}
+
+func TestVaultSecrets(t *testing.T) {
+	clearConfigEnv(t)
+	var password atomic.Value
+	password.Store("v1")
+	srv := vaultStub(t, &password)
+
+	t.Setenv("VAULT_ADDR", srv.URL)
+	t.Setenv("VAULT_TOKEN", "root-token")
+	t.Setenv("SEARCHFILTER_POSTGRES_PASSWORD", "vault:secret/data/search-filter#db_password")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PostgresPassword != "v1" || !cfg.HasSecretRefs() {
+		t.Fatalf("password = %q", cfg.PostgresPassword)
+	}
+
+	password.Store("v2")
+	fresh, err := cfg.Refreshed(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh.PostgresPassword != "v2" || fresh.PostgresDSN() == cfg.PostgresDSN() {
+		t.Errorf("rotated password not picked up: %q", fresh.PostgresPassword)
+	}
+}
+
+func TestVaultErrors(t *testing.T) {
+	var password atomic.Value
+	password.Store("v1")
+	srv := vaultStub(t, &password)
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		token string
+		ref   string
+	}{
+		{name: "bad token", token: "wrong", ref: "secret/data/search-filter#db_password"},
+		{name: "unknown path", token: "root-token", ref: "secret/data/other#db_password"},
+		{name: "unknown field", token: "root-token", ref: "secret/data/search-filter#api_key"},
+		{name: "no field", token: "root-token", ref: "secret/data/search-filter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewVault(srv.URL, tt.token, "").Secret(ctx, tt.ref); err == nil {
+				t.Error("want error")
+			}
+		})
+	}
+}
+
+func TestVaultRefWithoutAddr(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("SEARCHFILTER_POSTGRES_PASSWORD", "vault:secret/data/search-filter#db_password")
+
+	if _, err := Load(); err == nil {
+		t.Fatal("want error for vault reference without vault_addr")
+	}
+}
+
+type staticProvider map[string]string
+
+func (p staticProvider) Secret(_ context.Context, ref string) (string, error) {
+	return p[ref], nil
+}
+
+func TestCustomSecretProvider(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("SEARCHFILTER_POSTGRES_PASSWORD", "static:db")
+
+	cfg, err := Load(WithSecretProvider("static", staticProvider{"db": "s3cret"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PostgresPassword != "s3cret" {
+		t.Errorf("password = %q", cfg.PostgresPassword)
+	}
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Vault читает секреты из KV-хранилища HashiCorp Vault или совместимого HTTP API
+// (OpenBao). Ссылка — "<путь>#<поле>"; для KV v2 путь включает data/:
+// "secret/data/search-filter#db_password".
+type Vault struct {
+	addr      string
+	token     string
+	namespace string
+	client    *http.Client
+}
+
+func NewVault(addr, token, namespace string) *Vault {
+	return &Vault{
+		addr:      strings.TrimRight(addr, "/"),
+		token:     token,
+		namespace: namespace,
+		client:    &http.Client{Timeout: secretTimeout},
+	}
+}
+
+func (v *Vault) Secret(ctx context.Context, ref string) (string, error) {
+	path, field, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || field == "" {
+		return "", fmt.Errorf("vault ref %q: want <path>#<field>", ref)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.addr+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("vault %s: %s: %s", path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var body struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("vault %s: %w", path, err)
+	}
+	data := body.Data
+	// KV v2 кладёт значения в data.data рядом с data.metadata, KV v1 — прямо в data.
+	if inner, ok := data["data"].(map[string]any); ok {
+		if _, v2 := data["metadata"]; v2 {
+			data = inner
+		}
+	}
+	val, ok := data[field].(string)
+	if !ok {
+		return "", fmt.Errorf("vault %s: no string field %q", path, field)
+	}
+	return val, nil
+}
@@ -0,0 +1,167 @@
+package elastic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"search-filter/pkg/types"
+)
+
+var ErrUnsupported = errors.New("unsupported query value")
+
+// Kind определяет, в какое выражение Elasticsearch превращается ключ запроса.
+type Kind string
+
+const (
+	KindTerm  Kind = "term"
+	KindTerms Kind = "terms"
+	KindMatch Kind = "match"
+	KindGTE   Kind = "gte"
+	KindGT    Kind = "gt"
+	KindLTE   Kind = "lte"
+	KindLT    Kind = "lt"
+)
+
+type Field struct {
+	Field string `mapstructure:"field" json:"field"`
+	Kind  Kind   `mapstructure:"kind"  json:"kind"`
+}
+
+// Mapping сопоставляет ключи types.Query полям индекса.
+// Ключи без явного сопоставления компилируются в term/terms по одноимённому полю.
+type Mapping map[string]Field
+
+func DefaultMapping() Mapping {
+	return Mapping{
+		"tags":      {Field: "tags", Kind: KindTerms},
+		"date_from": {Field: "date", Kind: KindGTE},
+		"date_to":   {Field: "date", Kind: KindLTE},
+		"text":      {Field: "text", Kind: KindMatch},
+	}
+}
+
+// Merge возвращает копию m, дополненную сопоставлениями из override.
+func (m Mapping) Merge(override Mapping) Mapping {
+	res := make(Mapping, len(m)+len(override))
+	for k, f := range m {
+		res[k] = f
+	}
+	for k, f := range override {
+		res[k] = f
+	}
+	return res
+}
+
+func (m Mapping) Validate() error {
+	for k, f := range m {
+		if f.Field == "" {
+			return fmt.Errorf("elastic: mapping %q: empty field", k)
+		}
+		switch f.Kind {
+		case KindTerm, KindTerms, KindMatch, KindGTE, KindGT, KindLTE, KindLT:
+		default:
+			return fmt.Errorf("elastic: mapping %q: unknown kind %q", k, f.Kind)
+		}
+	}
+	return nil
+}
+
+// Compile переводит отрендеренный запрос в тело поиска Elasticsearch/OpenSearch
+// вида {"query":{"bool":{...}}}. Полнотекстовые условия попадают в must,
+// остальные — в filter. Ключи со значением null и пустыми массивами пропускаются.
+func Compile(q types.Query, m Mapping) (map[string]any, error) {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var (
+		must   []any
+		filter []any
+		ranges = map[string]map[string]any{}
+		order  []string
+	)
+	for _, k := range keys {
+		v := q[k]
+		if v == nil {
+			continue
+		}
+		f, ok := m[k]
+		if !ok {
+			f = Field{Field: k, Kind: KindTerm}
+			if _, isList := v.([]any); isList {
+				f.Kind = KindTerms
+			}
+		}
+
+		switch f.Kind {
+		case KindTerm:
+			if !isScalar(v) {
+				return nil, fmt.Errorf("%w: %q must be a scalar", ErrUnsupported, k)
+			}
+			filter = append(filter, map[string]any{"term": map[string]any{f.Field: v}})
+		case KindTerms:
+			list, ok := v.([]any)
+			if !ok {
+				if !isScalar(v) {
+					return nil, fmt.Errorf("%w: %q must be a scalar or a list", ErrUnsupported, k)
+				}
+				list = []any{v}
+			}
+			if len(list) == 0 {
+				continue
+			}
+			for _, it := range list {
+				if !isScalar(it) {
+					return nil, fmt.Errorf("%w: %q must contain only scalars", ErrUnsupported, k)
+				}
+			}
+			filter = append(filter, map[string]any{"terms": map[string]any{f.Field: list}})
+		case KindMatch:
+			if !isScalar(v) {
+				return nil, fmt.Errorf("%w: %q must be a scalar", ErrUnsupported, k)
+			}
+			must = append(must, map[string]any{"match": map[string]any{f.Field: v}})
+		case KindGTE, KindGT, KindLTE, KindLT:
+			if !isScalar(v) {
+				return nil, fmt.Errorf("%w: %q must be a scalar", ErrUnsupported, k)
+			}
+			r, ok := ranges[f.Field]
+			if !ok {
+				r = map[string]any{}
+				ranges[f.Field] = r
+				order = append(order, f.Field)
+			}
+			r[string(f.Kind)] = v
+		default:
+			return nil, fmt.Errorf("elastic: mapping %q: unknown kind %q", k, f.Kind)
+		}
+	}
+
+	for _, field := range order {
+		filter = append(filter, map[string]any{"range": map[string]any{field: ranges[field]}})
+	}
+
+	b := map[string]any{}
+	if len(must) > 0 {
+		b["must"] = must
+	}
+	if len(filter) > 0 {
+		b["filter"] = filter
+	}
+	if len(b) == 0 {
+		return map[string]any{"query": map[string]any{"match_all": map[string]any{}}}, nil
+	}
+	return map[string]any{"query": map[string]any{"bool": b}}, nil
+}
+
+func isScalar(v any) bool {
+	switch v.(type) {
+	case string, bool, float64, int, int64, json.Number:
+		return true
+	}
+	return false
+}
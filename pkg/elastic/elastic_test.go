@@ -0,0 +1,142 @@
+package elastic
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"search-filter/pkg/types"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      types.Query
+		mapping Mapping
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			in:   types.Query{},
+			want: `{"query":{"match_all":{}}}`,
+		},
+		{
+			name: "null and empty list skipped",
+			in:   types.Query{"author": nil, "tags": []any{}},
+			want: `{"query":{"match_all":{}}}`,
+		},
+		{
+			name: "unmapped scalar is term",
+			in:   types.Query{"author": "42"},
+			want: `{"query":{"bool":{"filter":[{"term":{"author":"42"}}]}}}`,
+		},
+		{
+			name: "unmapped list is terms",
+			in:   types.Query{"lang": []any{"go", "rust"}},
+			want: `{"query":{"bool":{"filter":[{"terms":{"lang":["go","rust"]}}]}}}`,
+		},
+		{
+			name: "terms wraps scalar",
+			in:   types.Query{"tags": "golang"},
+			want: `{"query":{"bool":{"filter":[{"terms":{"tags":["golang"]}}]}}}`,
+		},
+		{
+			name: "match goes to must",
+			in:   types.Query{"text": "search filters", "author": "42"},
+			want: `{"query":{"bool":{"filter":[{"term":{"author":"42"}}],"must":[{"match":{"text":"search filters"}}]}}}`,
+		},
+		{
+			name: "range bounds on one field are merged",
+			in:   types.Query{"date_from": "2025-01-01", "date_to": "2025-02-01"},
+			want: `{"query":{"bool":{"filter":[{"range":{"date":{"gte":"2025-01-01","lte":"2025-02-01"}}}]}}}`,
+		},
+		{
+			name: "strict range",
+			in:   types.Query{"min": 1.0, "max": 10.0},
+			mapping: Mapping{
+				"min": {Field: "price", Kind: KindGT},
+				"max": {Field: "price", Kind: KindLT},
+			},
+			want: `{"query":{"bool":{"filter":[{"range":{"price":{"gt":1,"lt":10}}}]}}}`,
+		},
+		{
+			name:    "mapped term field",
+			in:      types.Query{"user": "7"},
+			mapping: Mapping{"user": {Field: "author.id", Kind: KindTerm}},
+			want:    `{"query":{"bool":{"filter":[{"term":{"author.id":"7"}}]}}}`,
+		},
+		{
+			name:    "term with object",
+			in:      types.Query{"author": map[string]any{"id": 1}},
+			wantErr: true,
+		},
+		{
+			name:    "terms with nested list",
+			in:      types.Query{"tags": []any{[]any{"a"}}},
+			wantErr: true,
+		},
+		{
+			name:    "terms with object",
+			in:      types.Query{"tags": map[string]any{"a": 1}},
+			wantErr: true,
+		},
+		{
+			name:    "match with list",
+			in:      types.Query{"text": []any{"a", "b"}},
+			wantErr: true,
+		},
+		{
+			name:    "range with list",
+			in:      types.Query{"date_from": []any{"2025-01-01"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := DefaultMapping().Merge(tt.mapping)
+			got, err := Compile(tt.in, m)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnsupported) {
+					t.Fatalf("want ErrUnsupported, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			b, _ := json.Marshal(got)
+			if string(b) != tt.want {
+				t.Errorf("got  %s\nwant %s", b, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileUnknownKind(t *testing.T) {
+	_, err := Compile(types.Query{"x": 1}, Mapping{"x": {Field: "x", Kind: "prefix"}})
+	if err == nil || errors.Is(err, ErrUnsupported) {
+		t.Errorf("want mapping error, got %v", err)
+	}
+}
+
+func TestMappingMerge(t *testing.T) {
+	base := DefaultMapping()
+	m := base.Merge(Mapping{"text": {Field: "body", Kind: KindMatch}, "q": {Field: "title", Kind: KindMatch}})
+	if m["text"].Field != "body" || m["q"].Field != "title" || m["tags"].Field != "tags" {
+		t.Errorf("merge = %+v", m)
+	}
+	if base["text"].Field != "text" {
+		t.Error("Merge modified the receiver")
+	}
+	if err := m.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+	if err := (Mapping{"x": {Field: "x", Kind: "prefix"}}).Validate(); err == nil {
+		t.Error("unknown kind accepted")
+	}
+	if err := (Mapping{"x": {Kind: KindTerm}}).Validate(); err == nil {
+		t.Error("empty field accepted")
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"search-filter/pkg/repository"
+	"search-filter/pkg/types"
+
+	"github.com/google/uuid"
+)
+
+type batchOpDTO struct {
+	Op    string      `json:"op" enum:"create,update,delete"`
+	ID    uuid.UUID   `json:"id,omitempty" doc:"Required for update and delete; optional explicit ID for create"`
+	Name  string      `json:"name,omitempty" doc:"Required for create; renames the filter on update"`
+	Query types.Query `json:"query,omitempty" doc:"Required for create and update"`
+	Force bool        `json:"force,omitempty" doc:"Delete even if other filters reference this one"`
+}
+
+type batchBody struct {
+	Atomic     bool         `json:"atomic,omitempty" doc:"Roll back the whole batch if any operation fails"`
+	Operations []batchOpDTO `json:"operations" minItems:"1" maxItems:"500"`
+}
+type batchInput struct {
+	Body batchBody `json:"body"`
+}
+
+type batchItemDTO struct {
+	Index  int        `json:"index"`
+	Op     string     `json:"op"`
+	Status int        `json:"status"`
+	Filter *FilterDTO `json:"filter,omitempty"`
+	Error  string     `json:"error,omitempty"`
+	Code   string     `json:"code,omitempty" doc:"Stable error code, as in problem responses"`
+}
+type batchResultBody struct {
+	Succeeded int            `json:"succeeded"`
+	Failed    int            `json:"failed"`
+	Results   []batchItemDTO `json:"results"`
+}
+type batchOutput struct {
+	Body batchResultBody `json:"body"`
+}
+
+func (h *FiltersHandler) Batch(ctx context.Context, in *batchInput) (*batchOutput, error) {
+	ops := make([]repository.BatchOp, 0, len(in.Body.Operations))
+	for _, op := range in.Body.Operations {
+		ops = append(ops, repository.BatchOp{
+			Kind:  repository.BatchOpKind(op.Op),
+			ID:    op.ID,
+			Name:  op.Name,
+			Query: op.Query,
+			Force: op.Force,
+		})
+	}
+
+	res, err := h.svc.Batch(ctx, ops, in.Body.Atomic)
+	if err != nil {
+		return nil, problem(ctx, err)
+	}
+
+	out := batchResultBody{Results: make([]batchItemDTO, 0, len(res))}
+	for i, r := range res {
+		item := batchItemDTO{Index: i, Op: in.Body.Operations[i].Op}
+		item.Status, item.Code, item.Error = batchItemStatus(ops[i].Kind, r.Err)
+		if r.Filter != nil {
+			dto := toFilterDTO(*r.Filter)
+			item.Filter = &dto
+		}
+		if r.Err == nil {
+			out.Succeeded++
+		} else {
+			out.Failed++
+		}
+		out.Results = append(out.Results, item)
+	}
+	return &batchOutput{Body: out}, nil
+}
+
+func batchItemStatus(kind repository.BatchOpKind, err error) (int, string, string) {
+	switch {
+	case err == nil && kind == repository.BatchCreate:
+		return http.StatusCreated, "", ""
+	case err == nil && kind == repository.BatchDelete:
+		return http.StatusNoContent, "", ""
+	case err == nil:
+		return http.StatusOK, "", ""
+	}
+	return classify(err)
+}
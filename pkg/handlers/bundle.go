@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"search-filter/pkg/bundle"
+	"search-filter/pkg/service"
+
+	"github.com/google/uuid"
+)
+
+type exportInput struct {
+	Format string `query:"format" enum:"json,yaml" default:"json"`
+	IDs    string `query:"ids" doc:"Comma-separated filter IDs; all filters when empty"`
+}
+type exportOutput struct {
+	ContentType string `header:"Content-Type"`
+	Body        []byte
+}
+
+func (h *FiltersHandler) Export(ctx context.Context, in *exportInput) (*exportOutput, error) {
+	var ids []uuid.UUID
+	for _, raw := range strings.Split(in.IDs, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, problem(ctx, service.Invalid(service.CodeInvalidID, fmt.Sprintf("invalid id %q", raw),
+				service.FieldError{Field: "ids", Message: err.Error()}))
+		}
+		ids = append(ids, id)
+	}
+
+	b, err := h.svc.Export(ctx, ids)
+	if err != nil {
+		return nil, problem(ctx, err)
+	}
+
+	var buf bytes.Buffer
+	if err := bundle.Encode(&buf, b, in.Format); err != nil {
+		return nil, problem(ctx, err)
+	}
+	return &exportOutput{ContentType: bundle.ContentType(in.Format), Body: buf.Bytes()}, nil
+}
+
+type importInput struct {
+	Strategy    string `query:"strategy" enum:"skip,overwrite,rename" default:"skip"`
+	ContentType string `header:"Content-Type"`
+	RawBody     []byte `contentType:"application/yaml" doc:"JSON or YAML bundle"`
+}
+type importOutput struct {
+	Body *service.ImportReport `json:"body"`
+}
+
+func (h *FiltersHandler) Import(ctx context.Context, in *importInput) (*importOutput, error) {
+	b, err := bundle.Decode(in.RawBody, bundle.FormatFromContentType(in.ContentType))
+	if err != nil {
+		return nil, problem(ctx, service.Invalid(service.CodeValidation, err.Error(),
+			service.FieldError{Field: "body", Message: err.Error()}))
+	}
+
+	report, err := h.svc.Import(ctx, b, service.ConflictStrategy(in.Strategy))
+	if err != nil {
+		return nil, problem(ctx, err)
+	}
+	return &importOutput{Body: report}, nil
+}
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"context"
+
+	"search-filter/pkg/cache"
+)
+
+type CacheHandler struct {
+	cache *cache.Cache
+}
+
+func NewCacheHandler(c *cache.Cache) *CacheHandler {
+	return &CacheHandler{cache: c}
+}
+
+type cacheStatsOutput struct {
+	Body cache.Stats `json:"body"`
+}
+
+func (h *CacheHandler) Stats(ctx context.Context, _ *struct{}) (*cacheStatsOutput, error) {
+	return &cacheStatsOutput{Body: h.cache.Stats()}, nil
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+
+	"search-filter/pkg/models"
+	"search-filter/pkg/service"
+	"search-filter/pkg/webhook"
+)
+
+type EventsHandler struct {
+	svc service.Events
+}
+
+func NewEventsHandler(svc service.Events) *EventsHandler {
+	return &EventsHandler{svc: svc}
+}
+
+type listEventsInput struct {
+	Since int64 `query:"since" minimum:"0" doc:"Return events with id greater than this; pass next_since from the previous page"`
+	Limit int   `query:"limit" minimum:"1" maximum:"1000" default:"100"`
+}
+type listEventsBody struct {
+	Events    []models.Event `json:"events"`
+	NextSince int64          `json:"next_since"`
+}
+type listEventsOutput struct {
+	Body listEventsBody `json:"body"`
+}
+
+func (h *EventsHandler) List(ctx context.Context, in *listEventsInput) (*listEventsOutput, error) {
+	evs, err := h.svc.List(ctx, in.Since, in.Limit)
+	if err != nil {
+		return nil, problem(ctx, err)
+	}
+	next := in.Since
+	if len(evs) > 0 {
+		next = evs[len(evs)-1].ID
+	}
+	return &listEventsOutput{Body: listEventsBody{Events: evs, NextSince: next}}, nil
+}
+
+type WebhooksHandler struct {
+	d *webhook.Dispatcher
+}
+
+func NewWebhooksHandler(d *webhook.Dispatcher) *WebhooksHandler {
+	return &WebhooksHandler{d: d}
+}
+
+type deadLettersInput struct {
+	Limit int `query:"limit" minimum:"1" maximum:"1000" default:"100"`
+}
+type deadLettersOutput struct {
+	Body []webhook.DeadLetter `json:"body"`
+}
+
+func (h *WebhooksHandler) DeadLetters(ctx context.Context, in *deadLettersInput) (*deadLettersOutput, error) {
+	res, err := h.d.DeadLetters(ctx, in.Limit)
+	if err != nil {
+		return nil, problem(ctx, err)
+	}
+	return &deadLettersOutput{Body: res}, nil
+}
+
+type retryDeliveryInput struct {
+	ID int64 `path:"id"`
+}
+type retryDeliveryOutput struct{}
+
+func (h *WebhooksHandler) Retry(ctx context.Context, in *retryDeliveryInput) (*retryDeliveryOutput, error) {
+	if err := h.d.Retry(ctx, in.ID); err != nil {
+		return nil, problem(ctx, err)
+	}
+	return &retryDeliveryOutput{}, nil
+}
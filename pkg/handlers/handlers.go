@@ -2,23 +2,24 @@ package handlers
 
 import (
 	"context"
-	"errors"
 	"time"
 
+	"search-filter/pkg/backend"
+	"search-filter/pkg/elastic"
 	"search-filter/pkg/models"
 	"search-filter/pkg/service"
 	"search-filter/pkg/types"
 
-	"github.com/danielgtaylor/huma/v2"
 	"github.com/google/uuid"
 )
 
 type FiltersHandler struct {
 	svc service.Filters
+	es  elastic.Mapping
 }
 
-func NewFiltersHandler(svc service.Filters) *FiltersHandler {
-	return &FiltersHandler{svc: svc}
+func NewFiltersHandler(svc service.Filters, es elastic.Mapping) *FiltersHandler {
+	return &FiltersHandler{svc: svc, es: es}
 }
 
 type FilterDTO struct {
@@ -57,12 +58,7 @@ type createFilterOutput struct {
 func (h *FiltersHandler) Create(ctx context.Context, in *createFilterInput) (*createFilterOutput, error) {
 	f, err := h.svc.Create(ctx, in.Body.Name, in.Body.Query)
 	if err != nil {
-		switch {
-		case errors.Is(err, service.ErrValidation):
-			return nil, huma.Error422UnprocessableEntity(err.Error())
-		default:
-			return nil, huma.Error500InternalServerError("internal error")
-		}
+		return nil, problem(ctx, err)
 	}
 	return &createFilterOutput{Body: toFilterDTO(*f)}, nil
 }
@@ -74,7 +70,7 @@ type listFiltersOutput struct {
 func (h *FiltersHandler) List(ctx context.Context, _ *struct{}) (*listFiltersOutput, error) {
 	items, err := h.svc.List(ctx)
 	if err != nil {
-		return nil, huma.Error500InternalServerError("internal error")
+		return nil, problem(ctx, err)
 	}
 	out := make([]FilterListItemDTO, 0, len(items))
 	for _, it := range items {
@@ -100,14 +96,7 @@ type getFilterOutput struct {
 func (h *FiltersHandler) Get(ctx context.Context, in *getFilterInput) (*getFilterOutput, error) {
 	f, err := h.svc.Get(ctx, in.ID)
 	if err != nil {
-		switch {
-		case errors.Is(err, service.ErrNotFound):
-			return nil, huma.Error404NotFound("not found")
-		case errors.Is(err, service.ErrValidation):
-			return nil, huma.Error422UnprocessableEntity(err.Error())
-		default:
-			return nil, huma.Error500InternalServerError("internal error")
-		}
+		return nil, problem(ctx, err)
 	}
 	return &getFilterOutput{Body: toFilterDTO(*f)}, nil
 }
@@ -126,54 +115,68 @@ type updateFilterOutput struct {
 func (h *FiltersHandler) Update(ctx context.Context, in *updateFilterInput) (*updateFilterOutput, error) {
 	f, err := h.svc.Update(ctx, in.ID, in.Body.Query)
 	if err != nil {
-		switch {
-		case errors.Is(err, service.ErrNotFound):
-			return nil, huma.Error404NotFound("not found")
-		case errors.Is(err, service.ErrValidation):
-			return nil, huma.Error422UnprocessableEntity(err.Error())
-		default:
-			return nil, huma.Error500InternalServerError("internal error")
-		}
+		return nil, problem(ctx, err)
 	}
 	return &updateFilterOutput{Body: toFilterDTO(*f)}, nil
 }
 
 type deleteFilterInput struct {
 	IdPath
+	Force bool `query:"force" doc:"Delete even if other filters reference this one"`
 }
 
 func (h *FiltersHandler) Delete(ctx context.Context, in *deleteFilterInput) (*struct{}, error) {
-	if err := h.svc.Delete(ctx, in.ID); err != nil {
-		switch {
-		case errors.Is(err, service.ErrNotFound):
-			return nil, huma.Error404NotFound("not found")
-		case errors.Is(err, service.ErrValidation):
-			return nil, huma.Error422UnprocessableEntity(err.Error())
-		default:
-			return nil, huma.Error500InternalServerError("internal error")
-		}
+	if err := h.svc.Delete(ctx, in.ID, in.Force); err != nil {
+		return nil, problem(ctx, err)
 	}
 	return nil, nil
 }
 
+const (
+	FormatQuery         = "query"
+	FormatElasticsearch = "elasticsearch"
+)
+
 type applyFilterInput struct {
 	IdPath
+	Format string `query:"format" enum:"query,elasticsearch" default:"query" doc:"Output format of the rendered query"`
 }
 type applyFilterOutput struct {
-	Body types.Query `json:"body"`
+	Body any `json:"body"`
 }
 
 func (h *FiltersHandler) Apply(ctx context.Context, in *applyFilterInput) (*applyFilterOutput, error) {
 	q, err := h.svc.Apply(ctx, in.ID)
 	if err != nil {
-		switch {
-		case errors.Is(err, service.ErrNotFound):
-			return nil, huma.Error404NotFound("not found")
-		case errors.Is(err, service.ErrValidation):
-			return nil, huma.Error422UnprocessableEntity(err.Error())
-		default:
-			return nil, huma.Error500InternalServerError("internal error")
+		return nil, problem(ctx, err)
+	}
+	if in.Format == FormatElasticsearch {
+		es, err := elastic.Compile(q, h.es)
+		if err != nil {
+			return nil, problem(ctx, service.Invalid(service.CodeUnsupportedQuery, err.Error()))
 		}
+		return &applyFilterOutput{Body: es}, nil
 	}
 	return &applyFilterOutput{Body: q}, nil
 }
+
+type resultsInput struct {
+	IdPath
+	Cursor string `query:"cursor" doc:"Opaque cursor from the previous page"`
+	Limit  int    `query:"limit" minimum:"1" maximum:"100" default:"20"`
+}
+type resultsBody struct {
+	Items      []backend.Document `json:"items"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+type resultsOutput struct {
+	Body resultsBody `json:"body"`
+}
+
+func (h *FiltersHandler) Results(ctx context.Context, in *resultsInput) (*resultsOutput, error) {
+	docs, next, err := h.svc.Results(ctx, in.ID, backend.Page{Cursor: in.Cursor, Limit: in.Limit})
+	if err != nil {
+		return nil, problem(ctx, err)
+	}
+	return &resultsOutput{Body: resultsBody{Items: docs, NextCursor: next}}, nil
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"search-filter/pkg/logging"
+	"search-filter/pkg/service"
+	"search-filter/pkg/webhook"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// Problem — ответ об ошибке в формате RFC 7807 (application/problem+json):
+// поля huma.ErrorModel, стабильный машиночитаемый code и instance с request ID.
+type Problem struct {
+	huma.ErrorModel
+	Code string `json:"code" example:"filter_not_found" doc:"Stable machine-readable error code"`
+
+	headers http.Header
+}
+
+// GetHeaders отдаёт huma заголовки ответа, например Retry-After.
+func (p *Problem) GetHeaders() http.Header {
+	return p.headers
+}
+
+func init() {
+	// Ошибки, которые формирует сама huma (валидация запроса по схеме, 406 и т.п.),
+	// получают тот же формат, что и ошибки сервиса.
+	huma.NewError = func(status int, msg string, errs ...error) huma.StatusError {
+		return newProblem(status, statusCode(status), msg, errs...)
+	}
+	huma.NewErrorWithContext = func(ctx huma.Context, status int, msg string, errs ...error) huma.StatusError {
+		se := huma.NewError(status, msg, errs...)
+		if p, ok := se.(*Problem); ok && ctx != nil {
+			p.Instance = instance(ctx.Context())
+		}
+		return se
+	}
+}
+
+func newProblem(status int, code, detail string, errs ...error) *Problem {
+	p := &Problem{
+		ErrorModel: huma.ErrorModel{Status: status, Title: http.StatusText(status), Detail: detail},
+		Code:       code,
+	}
+	for _, err := range errs {
+		if err != nil {
+			p.Add(err)
+		}
+	}
+	return p
+}
+
+// statusCode — код для ошибок, у которых нет доменного кода: snake_case статуса HTTP.
+func statusCode(status int) string {
+	switch status {
+	case http.StatusUnprocessableEntity:
+		return service.CodeValidation
+	case http.StatusInternalServerError:
+		return service.CodeInternal
+	}
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
+
+// instance идентифицирует конкретный случай ошибки по request ID; по нему же
+// находятся записи журнала.
+func instance(ctx context.Context) string {
+	if id := logging.RequestID(ctx); id != "" {
+		return "urn:request:" + id
+	}
+	return ""
+}
+
+// classify сопоставляет ошибку сервиса статусу HTTP, коду и тексту для клиента.
+// Для неизвестных ошибок текст скрыт.
+func classify(err error) (status int, code, detail string) {
+	err = service.FromRepository(err)
+	var se *service.Error
+	if errors.As(err, &se) {
+		return kindStatus(se.Kind), se.Code, se.Error()
+	}
+	switch {
+	case errors.Is(err, service.ErrNotFound), errors.Is(err, webhook.ErrNotFound):
+		return http.StatusNotFound, service.CodeNotFound, err.Error()
+	case errors.Is(err, service.ErrAborted):
+		return http.StatusFailedDependency, service.CodeAborted, err.Error()
+	case errors.Is(err, service.ErrNoBackend):
+		return http.StatusNotImplemented, service.CodeNoBackend, err.Error()
+	case errors.Is(err, service.ErrBackend):
+		return http.StatusBadGateway, service.CodeBackend, "search backend error"
+	}
+	if status := kindStatus(err); status != http.StatusInternalServerError {
+		return status, statusCode(status), err.Error()
+	}
+	return http.StatusInternalServerError, service.CodeInternal, "internal error"
+}
+
+func kindStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, service.ErrRateLimited):
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
+
+// problem превращает ошибку сервиса в ответ RFC 7807. Причину внутренних ошибок
+// пишет в журнал с request_id запроса; клиенту она не показывается.
+func problem(ctx context.Context, err error) error {
+	status, code, detail := classify(err)
+	if code == service.CodeInternal {
+		slog.ErrorContext(ctx, "internal error", "error", err)
+	}
+
+	p := newProblem(status, code, detail)
+	p.Instance = instance(ctx)
+	var se *service.Error
+	if errors.As(err, &se) {
+		for _, f := range se.Fields {
+			p.Errors = append(p.Errors, &huma.ErrorDetail{Location: f.Field, Message: f.Message})
+		}
+		if se.RetryAfter > 0 {
+			secs := int(math.Ceil(se.RetryAfter.Seconds()))
+			p.headers = http.Header{"Retry-After": {strconv.Itoa(secs)}}
+		}
+	}
+	return p
+}
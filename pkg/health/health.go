@@ -0,0 +1,126 @@
+// Package health собирает проверки зависимостей сервиса для проб готовности:
+// сервис готов, если все проверки прошли или только предупреждают и он не
+// останавливается.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+	// StatusWarn — проверка нашла проблему, которая не мешает обслуживать запросы.
+	StatusWarn = "warn"
+	// StatusShuttingDown — сервис завершает работу; проверки при этом не выполняются.
+	StatusShuttingDown = "shutting_down"
+)
+
+// Check проверяет одну зависимость; ошибка делает сервис неготовым.
+type Check func(ctx context.Context) error
+
+// Warn помечает ошибку проверки как некритичную: она попадает в сводку со
+// статусом warn, но сервис остаётся готовым.
+func Warn(err error) error {
+	if err == nil {
+		return nil
+	}
+	return warning{err}
+}
+
+type warning struct{ error }
+
+func (w warning) Unwrap() error { return w.error }
+
+type named struct {
+	name  string
+	check Check
+}
+
+// Checker выполняет проверки параллельно, каждую — не дольше timeout.
+type Checker struct {
+	timeout  time.Duration
+	checks   []named
+	stopping atomic.Bool
+}
+
+func New(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add регистрирует проверку; вызывается до начала обслуживания запросов.
+func (c *Checker) Add(name string, check Check) {
+	c.checks = append(c.checks, named{name: name, check: check})
+}
+
+// Shutdown переводит сервис в неготовое состояние до конца работы процесса,
+// чтобы балансировщик перестал направлять на него запросы.
+func (c *Checker) Shutdown() {
+	c.stopping.Store(true)
+}
+
+type Result struct {
+	Status   string  `json:"status"`
+	Error    string  `json:"error,omitempty"`
+	Duration float64 `json:"duration_ms"`
+}
+
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// OK сообщает, готов ли сервис.
+func (r Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+// Ready выполняет все проверки и возвращает их сводку.
+func (c *Checker) Ready(ctx context.Context) Report {
+	if c.stopping.Load() {
+		return Report{Status: StatusShuttingDown}
+	}
+
+	results := make([]Result, len(c.checks))
+	var wg sync.WaitGroup
+	for i, nc := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, nc.check)
+		}()
+	}
+	wg.Wait()
+
+	rep := Report{Status: StatusOK, Checks: make(map[string]Result, len(c.checks))}
+	for i, nc := range c.checks {
+		if results[i].Status == StatusFail {
+			rep.Status = StatusFail
+		}
+		rep.Checks[nc.name] = results[i]
+	}
+	return rep
+}
+
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	res := Result{Status: StatusOK, Duration: float64(time.Since(start).Microseconds()) / 1000}
+	var w warning
+	switch {
+	case errors.As(err, &w):
+		res.Status = StatusWarn
+		res.Error = err.Error()
+	case err != nil:
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+	return res
+}
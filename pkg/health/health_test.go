@@ -0,0 +1,65 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReady(t *testing.T) {
+	c := New(50 * time.Millisecond)
+	c.Add("db", func(context.Context) error { return nil })
+	c.Add("cache", func(context.Context) error { return errors.New("connection refused") })
+	c.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	rep := c.Ready(context.Background())
+	if rep.OK() {
+		t.Fatalf("report is ok: %+v", rep)
+	}
+	if got := rep.Checks["db"]; got.Status != StatusOK || got.Error != "" {
+		t.Errorf("db = %+v", got)
+	}
+	if got := rep.Checks["cache"]; got.Status != StatusFail || got.Error != "connection refused" {
+		t.Errorf("cache = %+v", got)
+	}
+	if got := rep.Checks["slow"]; got.Status != StatusFail || got.Error != context.DeadlineExceeded.Error() {
+		t.Errorf("slow = %+v", got)
+	}
+
+	c.Shutdown()
+	if rep := c.Ready(context.Background()); rep.Status != StatusShuttingDown || rep.Checks != nil {
+		t.Errorf("after Shutdown = %+v", rep)
+	}
+}
+
+func TestReadyWarning(t *testing.T) {
+	c := New(time.Second)
+	c.Add("db", func(context.Context) error { return nil })
+	c.Add("schema", func(context.Context) error { return Warn(errors.New("schema is newer")) })
+
+	rep := c.Ready(context.Background())
+	if !rep.OK() {
+		t.Fatalf("warning made the service unready: %+v", rep)
+	}
+	if got := rep.Checks["schema"]; got.Status != StatusWarn || got.Error != "schema is newer" {
+		t.Errorf("schema = %+v", got)
+	}
+
+	c.Add("cache", func(context.Context) error { return errors.New("connection refused") })
+	if rep := c.Ready(context.Background()); rep.OK() {
+		t.Errorf("failure with a warning is ok: %+v", rep)
+	}
+	if Warn(nil) != nil {
+		t.Error("Warn(nil) != nil")
+	}
+}
+
+func TestReadyWithoutChecks(t *testing.T) {
+	if rep := New(time.Second).Ready(context.Background()); !rep.OK() {
+		t.Errorf("report = %+v", rep)
+	}
+}
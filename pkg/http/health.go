@@ -0,0 +1,24 @@
+package http
+
+import (
+	"search-filter/pkg/health"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// livez отвечает, пока процесс обслуживает запросы. Зависимости здесь не
+// проверяются: недоступность БД — повод убрать реплику из балансировки (/readyz),
+// а не перезапускать её.
+func livez(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"status": health.StatusOK})
+}
+
+// readyz возвращает сводку проверок зависимостей; 503, если хоть одна не прошла
+// или сервер останавливается.
+func (s *Server) readyz(c *fiber.Ctx) error {
+	rep := s.health.Ready(c.UserContext())
+	if !rep.OK() {
+		c.Status(fiber.StatusServiceUnavailable)
+	}
+	return c.JSON(rep)
+}
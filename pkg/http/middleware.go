@@ -0,0 +1,132 @@
+package http
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"search-filter/pkg/logging"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/google/uuid"
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const HeaderRequestID = "X-Request-ID"
+
+// maxRequestIDLen ограничивает длину входящего X-Request-ID.
+const maxRequestIDLen = 128
+
+// requestLogger берёт request ID из X-Request-ID или выдаёт новый, возвращает его
+// в ответе, кладёт в контекст запроса (его видят сервис и репозиторий) и пишет
+// запись о каждом запросе.
+func requestLogger(c *fiber.Ctx) error {
+	id := c.Get(HeaderRequestID)
+	if !validRequestID(id) {
+		id = uuid.NewString()
+	}
+	c.Set(HeaderRequestID, id)
+	ctx := logging.WithRequestID(c.UserContext(), id)
+	c.SetUserContext(ctx)
+
+	start := time.Now()
+	err := c.Next()
+
+	slog.LogAttrs(ctx, slog.LevelInfo, "http request",
+		slog.String("method", c.Method()),
+		slog.String("path", c.Path()),
+		slog.Int("status", responseStatus(c, err)),
+		slog.Duration("duration", time.Since(start)),
+	)
+	return err
+}
+
+// responseStatus возвращает код ответа; ответ об ошибке пишет ErrorHandler
+// уже после middleware, поэтому код берётся из ошибки.
+func responseStatus(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+	var fe *fiber.Error
+	if errors.As(err, &fe) {
+		return fe.Code
+	}
+	return fiber.StatusInternalServerError
+}
+
+var tracer = otel.Tracer("search-filter/pkg/http")
+
+// traceRequests открывает серверный спан на каждый запрос, продолжая трассу из
+// заголовка traceparent, и кладёт его в контекст запроса. Строки fiber живут
+// только до конца запроса, а спан экспортируется позже, поэтому они копируются.
+func traceRequests(c *fiber.Ctx) error {
+	ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), headerCarrier{&c.Request().Header})
+	method := utils.CopyString(c.Method())
+	ctx, span := tracer.Start(ctx, method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(method),
+			semconv.URLPath(utils.CopyString(c.Path())),
+			attribute.String("request_id", logging.RequestID(ctx)),
+		),
+	)
+	defer span.End()
+	c.SetUserContext(ctx)
+
+	err := c.Next()
+
+	status := responseStatus(c, err)
+	// Шаблон маршрута известен только после сопоставления; экранирование
+	// пользовательских методов (verbRouter) в нём не нужно.
+	route := strings.ReplaceAll(c.Route().Path, `\:`, ":")
+	span.SetName(method + " " + route)
+	span.SetAttributes(semconv.HTTPRoute(route), semconv.HTTPResponseStatusCode(status))
+	if status >= fiber.StatusInternalServerError {
+		span.SetStatus(codes.Error, utils.StatusMessage(status))
+	}
+	return err
+}
+
+// headerCarrier даёт пропагатору доступ к заголовкам запроса fasthttp.
+type headerCarrier struct {
+	h *fasthttp.RequestHeader
+}
+
+func (c headerCarrier) Get(key string) string {
+	return string(c.h.Peek(key))
+}
+
+func (c headerCarrier) Set(key, value string) {
+	c.h.Set(key, value)
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, c.h.Len())
+	c.h.VisitAll(func(k, _ []byte) {
+		keys = append(keys, string(k))
+	})
+	return keys
+}
+
+// validRequestID пропускает только безопасные символы: ID попадает в журналы
+// и в комментарий SQL-запроса.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
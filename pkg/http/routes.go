@@ -1,19 +1,34 @@
 package http
 
 import (
+	"search-filter/pkg/cache"
+	"search-filter/pkg/elastic"
 	"search-filter/pkg/handlers"
 	"search-filter/pkg/service"
+	"search-filter/pkg/webhook"
 
 	"github.com/danielgtaylor/huma/v2"
 )
 
-func RegisterRoutes(api huma.API, svc service.Filters) {
-	h := handlers.NewFiltersHandler(svc)
+func RegisterRoutes(api huma.API, svc service.Filters, es elastic.Mapping) {
+	h := handlers.NewFiltersHandler(svc, es)
 
 	huma.Post(api, "/filters", h.Create, func(op *huma.Operation) {
 		op.Description = "Create a saved search filter."
 	})
 
+	huma.Post(api, "/filters:batch", h.Batch, func(op *huma.Operation) {
+		op.Description = "Create, update and delete filters in one transaction with per-item results."
+	})
+
+	huma.Get(api, "/filters:export", h.Export, func(op *huma.Operation) {
+		op.Description = "Export filters as a versioned JSON or YAML bundle."
+	})
+
+	huma.Post(api, "/filters:import", h.Import, func(op *huma.Operation) {
+		op.Description = "Import a JSON or YAML bundle (Content-Type: application/yaml) with a conflict strategy."
+	})
+
 	huma.Get(api, "/filters", h.List, func(op *huma.Operation) {
 		op.Description = "List saved filters (no timestamps in items)."
 	})
@@ -27,10 +42,42 @@ func RegisterRoutes(api huma.API, svc service.Filters) {
 	})
 
 	huma.Delete(api, "/filters/{id}", h.Delete, func(op *huma.Operation) {
-		op.Description = "Delete a filter by ID (204 No Content)."
+		op.Description = "Delete a filter by ID (204 No Content, 409 if other filters reference it unless force=true)."
 	})
 
 	huma.Get(api, "/filters/{id}/apply", h.Apply, func(op *huma.Operation) {
-		op.Description = "Resolve placeholders and return a ready-to-use query."
+		op.Description = "Resolve placeholders and return a ready-to-use query (format=elasticsearch returns an Elasticsearch bool query)."
+	})
+
+	huma.Get(api, "/filters/{id}/results", h.Results, func(op *huma.Operation) {
+		op.Description = "Apply the filter and run it against the configured search backend."
+	})
+}
+
+func RegisterCacheRoutes(api huma.API, c *cache.Cache) {
+	h := handlers.NewCacheHandler(c)
+
+	huma.Get(api, "/cache/stats", h.Stats, func(op *huma.Operation) {
+		op.Description = "Cache hit/miss counters per key kind (filter, apply), invalidations and errors."
+	})
+}
+
+func RegisterEventRoutes(api huma.API, svc service.Events) {
+	h := handlers.NewEventsHandler(svc)
+
+	huma.Get(api, "/events", h.List, func(op *huma.Operation) {
+		op.Description = "Poll filter lifecycle events (filter.created, filter.updated, filter.deleted) after the given id."
+	})
+}
+
+func RegisterWebhookRoutes(api huma.API, d *webhook.Dispatcher) {
+	h := handlers.NewWebhooksHandler(d)
+
+	huma.Get(api, "/webhooks/dead-letters", h.DeadLetters, func(op *huma.Operation) {
+		op.Description = "List webhook deliveries that exhausted their retries."
+	})
+
+	huma.Post(api, "/webhooks/dead-letters/{id}/retry", h.Retry, func(op *huma.Operation) {
+		op.Description = "Requeue a dead-lettered delivery with a fresh retry budget (204 No Content)."
 	})
 }
@@ -0,0 +1,842 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"search-filter/pkg/backend"
+	"search-filter/pkg/cache"
+	"search-filter/pkg/config"
+	"search-filter/pkg/health"
+	httpapi "search-filter/pkg/http"
+	"search-filter/pkg/logging"
+	"search-filter/pkg/metrics"
+	"search-filter/pkg/models"
+	"search-filter/pkg/repository"
+	"search-filter/pkg/service"
+	"search-filter/pkg/storage"
+	"search-filter/pkg/tracing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const testUserID = 42
+
+func newTestApp(t *testing.T, opts ...service.Option) *fiber.App {
+	t.Helper()
+	svc, err := service.NewFiltersService(repository.NewMemoryRepository(), time.UTC, testUserID, opts...)
+	if err != nil {
+		t.Fatalf("NewFiltersService: %v", err)
+	}
+	return httpapi.NewServer(&config.Config{}, svc).App()
+}
+
+func do(t *testing.T, app *fiber.App, method, path, contentType, body string) (int, []byte) {
+	t.Helper()
+	var r io.Reader
+	if body != "" {
+		r = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, path, r)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	return resp.StatusCode, b
+}
+
+func doJSON(t *testing.T, app *fiber.App, method, path, body string, want int, out any) {
+	t.Helper()
+	ct := ""
+	if body != "" {
+		ct = "application/json"
+	}
+	status, b := do(t, app, method, path, ct, body)
+	if status != want {
+		t.Fatalf("%s %s = %d, want %d: %s", method, path, status, want, b)
+	}
+	if out != nil {
+		if err := json.Unmarshal(b, out); err != nil {
+			t.Fatalf("decode %s: %v", b, err)
+		}
+	}
+}
+
+type filterResp struct {
+	ID    string         `json:"id"`
+	Name  string         `json:"name"`
+	Query map[string]any `json:"query"`
+}
+
+func createFilter(t *testing.T, app *fiber.App, name, query string) filterResp {
+	t.Helper()
+	var f filterResp
+	doJSON(t, app, http.MethodPost, "/filters", `{"name":"`+name+`","query":`+query+`}`, http.StatusOK, &f)
+	return f
+}
+
+func TestHealthz(t *testing.T) {
+	app := newTestApp(t)
+	if status, _ := do(t, app, http.MethodGet, "/healthz", "", ""); status != http.StatusNoContent {
+		t.Errorf("GET /healthz = %d, want 204", status)
+	}
+}
+
+func TestCreateFilter(t *testing.T) {
+	app := newTestApp(t)
+
+	f := createFilter(t, app, "Go", `{"tags":["golang"]}`)
+	if f.ID == "" || f.Name != "Go" {
+		t.Errorf("created = %+v", f)
+	}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"empty name", `{"name":"","query":{"a":"b"}}`},
+		{"unknown ref", `{"name":"x","query":{"$ref":"6f1c4b8e-0000-4000-8000-000000000000"}}`},
+		{"bad ref", `{"name":"x","query":{"$ref":"nope"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doJSON(t, app, http.MethodPost, "/filters", tt.body, http.StatusUnprocessableEntity, nil)
+		})
+	}
+}
+
+func TestListFilters(t *testing.T) {
+	app := newTestApp(t)
+
+	var empty []filterResp
+	doJSON(t, app, http.MethodGet, "/filters", "", http.StatusOK, &empty)
+	if len(empty) != 0 {
+		t.Errorf("GET /filters = %d items, want 0", len(empty))
+	}
+
+	createFilter(t, app, "a", `{"x":"1"}`)
+	createFilter(t, app, "b", `{"x":"2"}`)
+
+	var list []filterResp
+	doJSON(t, app, http.MethodGet, "/filters", "", http.StatusOK, &list)
+	if len(list) != 2 {
+		t.Errorf("GET /filters = %d items, want 2", len(list))
+	}
+}
+
+func TestGetFilter(t *testing.T) {
+	app := newTestApp(t)
+	f := createFilter(t, app, "Go", `{"tags":["golang"]}`)
+
+	var got filterResp
+	doJSON(t, app, http.MethodGet, "/filters/"+f.ID, "", http.StatusOK, &got)
+	if got.ID != f.ID || got.Name != "Go" {
+		t.Errorf("GET = %+v, want %+v", got, f)
+	}
+
+	doJSON(t, app, http.MethodGet, "/filters/6f1c4b8e-0000-4000-8000-000000000000", "", http.StatusNotFound, nil)
+	doJSON(t, app, http.MethodGet, "/filters/not-a-uuid", "", http.StatusUnprocessableEntity, nil)
+}
+
+func TestUpdateFilter(t *testing.T) {
+	app := newTestApp(t)
+	f := createFilter(t, app, "Go", `{"tags":["golang"]}`)
+
+	var upd filterResp
+	doJSON(t, app, http.MethodPut, "/filters/"+f.ID, `{"query":{"tags":["db"]}}`, http.StatusOK, &upd)
+	if tags, _ := upd.Query["tags"].([]any); len(tags) != 1 || tags[0] != "db" {
+		t.Errorf("updated query = %v", upd.Query)
+	}
+
+	doJSON(t, app, http.MethodPut, "/filters/6f1c4b8e-0000-4000-8000-000000000000", `{"query":{"a":"b"}}`, http.StatusNotFound, nil)
+	doJSON(t, app, http.MethodPut, "/filters/"+f.ID, `{"query":{"$ref":"`+f.ID+`"}}`, http.StatusUnprocessableEntity, nil)
+}
+
+func TestDeleteFilter(t *testing.T) {
+	app := newTestApp(t)
+	base := createFilter(t, app, "base", `{"tags":["golang"]}`)
+	createFilter(t, app, "child", `{"$ref":"`+base.ID+`"}`)
+
+	doJSON(t, app, http.MethodDelete, "/filters/"+base.ID, "", http.StatusConflict, nil)
+	doJSON(t, app, http.MethodDelete, "/filters/"+base.ID+"?force=true", "", http.StatusNoContent, nil)
+	doJSON(t, app, http.MethodDelete, "/filters/"+base.ID, "", http.StatusNotFound, nil)
+}
+
+func TestApplyFilter(t *testing.T) {
+	app := newTestApp(t)
+	base := createFilter(t, app, "base", `{"tags":["golang"]}`)
+	f := createFilter(t, app, "mine", `{"$ref":"`+base.ID+`","author":"{{current_user}}","date_to":"{{today}}"}`)
+
+	var q map[string]any
+	doJSON(t, app, http.MethodGet, "/filters/"+f.ID+"/apply", "", http.StatusOK, &q)
+	if q["author"] != "42" {
+		t.Errorf("author = %v, want 42", q["author"])
+	}
+	if q["date_to"] != time.Now().UTC().Format("2006-01-02") {
+		t.Errorf("date_to = %v", q["date_to"])
+	}
+	if _, ok := q["tags"]; !ok {
+		t.Errorf("referenced filter not merged: %v", q)
+	}
+
+	var es map[string]any
+	doJSON(t, app, http.MethodGet, "/filters/"+base.ID+"/apply?format=elasticsearch", "", http.StatusOK, &es)
+	if _, ok := es["query"].(map[string]any)["bool"]; !ok {
+		t.Errorf("elasticsearch query = %v", es)
+	}
+
+	doJSON(t, app, http.MethodGet, "/filters/"+f.ID+"/apply?format=sql", "", http.StatusUnprocessableEntity, nil)
+	doJSON(t, app, http.MethodGet, "/filters/6f1c4b8e-0000-4000-8000-000000000000/apply", "", http.StatusNotFound, nil)
+}
+
+func TestFilterResults(t *testing.T) {
+	docs := []backend.Document{
+		{"id": "1", "tags": []any{"golang"}},
+		{"id": "2", "tags": []any{"db"}},
+		{"id": "3", "tags": []any{"golang", "db"}},
+	}
+	app := newTestApp(t, service.WithBackend(backend.NewMemory(docs, config.Config{}.ElasticsearchMapping())))
+	f := createFilter(t, app, "Go", `{"tags":["golang"]}`)
+
+	var page struct {
+		Items      []map[string]any `json:"items"`
+		NextCursor string           `json:"next_cursor"`
+	}
+	doJSON(t, app, http.MethodGet, "/filters/"+f.ID+"/results?limit=1", "", http.StatusOK, &page)
+	if len(page.Items) != 1 || page.NextCursor == "" {
+		t.Fatalf("first page = %+v", page)
+	}
+	var last struct {
+		Items      []map[string]any `json:"items"`
+		NextCursor string           `json:"next_cursor"`
+	}
+	doJSON(t, app, http.MethodGet, "/filters/"+f.ID+"/results?limit=1&cursor="+page.NextCursor, "", http.StatusOK, &last)
+	if len(last.Items) != 1 || last.NextCursor != "" {
+		t.Errorf("last page = %+v", last)
+	}
+
+	doJSON(t, app, http.MethodGet, "/filters/"+f.ID+"/results?cursor=%21%21", "", http.StatusUnprocessableEntity, nil)
+
+	noBackend := newTestApp(t)
+	g := createFilter(t, noBackend, "Go", `{"tags":["golang"]}`)
+	doJSON(t, noBackend, http.MethodGet, "/filters/"+g.ID+"/results", "", http.StatusNotImplemented, nil)
+}
+
+func TestBatchFilters(t *testing.T) {
+	app := newTestApp(t)
+	f := createFilter(t, app, "Go", `{"tags":["golang"]}`)
+
+	var res struct {
+		Succeeded int `json:"succeeded"`
+		Failed    int `json:"failed"`
+		Results   []struct {
+			Status int `json:"status"`
+		} `json:"results"`
+	}
+	body := `{"operations":[
+		{"op":"create","name":"new","query":{"a":"b"}},
+		{"op":"update","id":"` + f.ID + `","query":{"tags":["db"]}},
+		{"op":"delete","id":"6f1c4b8e-0000-4000-8000-000000000000"}]}`
+	doJSON(t, app, http.MethodPost, "/filters:batch", body, http.StatusOK, &res)
+	if res.Succeeded != 2 || res.Failed != 1 {
+		t.Errorf("batch = %+v", res)
+	}
+	wantStatus := []int{http.StatusCreated, http.StatusOK, http.StatusNotFound}
+	for i, r := range res.Results {
+		if r.Status != wantStatus[i] {
+			t.Errorf("result %d status = %d, want %d", i, r.Status, wantStatus[i])
+		}
+	}
+
+	atomic := `{"atomic":true,"operations":[
+		{"op":"create","name":"other","query":{"a":"b"}},
+		{"op":"delete","id":"6f1c4b8e-0000-4000-8000-000000000000"}]}`
+	doJSON(t, app, http.MethodPost, "/filters:batch", atomic, http.StatusOK, &res)
+	if res.Succeeded != 0 || res.Results[0].Status != http.StatusFailedDependency {
+		t.Errorf("atomic batch = %+v", res)
+	}
+
+	var list []filterResp
+	doJSON(t, app, http.MethodGet, "/filters", "", http.StatusOK, &list)
+	if len(list) != 2 {
+		t.Errorf("filters after batches = %d, want 2", len(list))
+	}
+
+	var dup struct {
+		Failed  int `json:"failed"`
+		Results []struct {
+			Status int    `json:"status"`
+			Code   string `json:"code"`
+		} `json:"results"`
+	}
+	reuse := `{"operations":[{"op":"create","id":"` + f.ID + `","name":"copy","query":{"a":"b"}}]}`
+	doJSON(t, app, http.MethodPost, "/filters:batch", reuse, http.StatusOK, &dup)
+	if dup.Failed != 1 || dup.Results[0].Status != http.StatusConflict || dup.Results[0].Code != "conflict" {
+		t.Errorf("create with an existing id = %+v, want 409 conflict", dup)
+	}
+
+	doJSON(t, app, http.MethodPost, "/filters:batch", `{"operations":[]}`, http.StatusUnprocessableEntity, nil)
+}
+
+func TestExportImport(t *testing.T) {
+	src := newTestApp(t)
+	base := createFilter(t, src, "base", `{"tags":["golang"]}`)
+	createFilter(t, src, "child", `{"$ref":"`+base.ID+`"}`)
+
+	status, yamlBundle := do(t, src, http.MethodGet, "/filters:export?format=yaml", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("GET /filters:export = %d: %s", status, yamlBundle)
+	}
+	status, jsonBundle := do(t, src, http.MethodGet, "/filters:export?ids="+base.ID, "", "")
+	if status != http.StatusOK {
+		t.Fatalf("GET /filters:export = %d: %s", status, jsonBundle)
+	}
+	var b struct {
+		Version int `json:"version"`
+		Filters []struct {
+			ID string `json:"id"`
+		} `json:"filters"`
+	}
+	if err := json.Unmarshal(jsonBundle, &b); err != nil {
+		t.Fatalf("decode bundle: %v", err)
+	}
+	if b.Version != 1 || len(b.Filters) != 1 || b.Filters[0].ID != base.ID {
+		t.Errorf("bundle = %+v", b)
+	}
+
+	dst := newTestApp(t)
+	var report struct {
+		Created int `json:"created"`
+		Skipped int `json:"skipped"`
+	}
+	importYAML := func(strategy string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/filters:import?strategy="+strategy, bytes.NewReader(yamlBundle))
+		req.Header.Set("Content-Type", "application/yaml")
+		resp, err := dst.Test(req, -1)
+		if err != nil {
+			t.Fatalf("POST /filters:import: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			t.Fatalf("POST /filters:import = %d: %s", resp.StatusCode, body)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+			t.Fatalf("decode report: %v", err)
+		}
+	}
+
+	importYAML("skip")
+	if report.Created != 2 {
+		t.Errorf("first import = %+v, want 2 created", report)
+	}
+	importYAML("skip")
+	if report.Created != 0 || report.Skipped != 2 {
+		t.Errorf("second import = %+v, want 2 skipped", report)
+	}
+
+	doJSON(t, dst, http.MethodGet, "/filters/"+base.ID, "", http.StatusOK, nil)
+	doJSON(t, dst, http.MethodPost, "/filters:import", `{"version":99,"filters":[]}`, http.StatusUnprocessableEntity, nil)
+}
+
+func TestCacheStats(t *testing.T) {
+	c := cache.New(cache.NewLRU(100), "test:", time.Minute)
+	svc, err := service.NewFiltersService(
+		repository.NewCachedRepository(repository.NewMemoryRepository(), c), time.UTC, testUserID, service.WithCache(c))
+	if err != nil {
+		t.Fatalf("NewFiltersService: %v", err)
+	}
+	app := httpapi.NewServer(&config.Config{}, svc, httpapi.WithCache(c)).App()
+
+	f := createFilter(t, app, "Go", `{"tags":["golang"]}`)
+	doJSON(t, app, http.MethodGet, "/filters/"+f.ID+"/apply", "", http.StatusOK, nil)
+	doJSON(t, app, http.MethodGet, "/filters/"+f.ID+"/apply", "", http.StatusOK, nil)
+
+	var st cache.Stats
+	doJSON(t, app, http.MethodGet, "/cache/stats", "", http.StatusOK, &st)
+	if a := st.Kinds["apply"]; a.Hits != 1 || a.Misses != 1 {
+		t.Errorf("apply stats = %+v", a)
+	}
+
+	doJSON(t, newTestApp(t), http.MethodGet, "/cache/stats", "", http.StatusNotFound, nil)
+}
+
+type fakeEventLog []models.Event
+
+func (l fakeEventLog) Events(_ context.Context, since int64, limit int) ([]models.Event, error) {
+	res := []models.Event{}
+	for _, ev := range l {
+		if ev.ID > since && len(res) < limit {
+			res = append(res, ev)
+		}
+	}
+	return res, nil
+}
+
+func (l fakeEventLog) LastEventID(context.Context) (int64, error) {
+	if len(l) == 0 {
+		return 0, nil
+	}
+	return l[len(l)-1].ID, nil
+}
+
+func TestListEvents(t *testing.T) {
+	svc, err := service.NewFiltersService(repository.NewMemoryRepository(), time.UTC, testUserID)
+	if err != nil {
+		t.Fatalf("NewFiltersService: %v", err)
+	}
+	events, err := service.NewEventsService(fakeEventLog{
+		{ID: 1, Type: models.EventFilterCreated},
+		{ID: 2, Type: models.EventFilterUpdated},
+		{ID: 3, Type: models.EventFilterDeleted},
+	})
+	if err != nil {
+		t.Fatalf("NewEventsService: %v", err)
+	}
+	app := httpapi.NewServer(&config.Config{}, svc, httpapi.WithEvents(events)).App()
+
+	var page struct {
+		Events []struct {
+			ID   int64  `json:"id"`
+			Type string `json:"type"`
+		} `json:"events"`
+		NextSince int64 `json:"next_since"`
+	}
+	doJSON(t, app, http.MethodGet, "/events?limit=2", "", http.StatusOK, &page)
+	if len(page.Events) != 2 || page.NextSince != 2 {
+		t.Fatalf("first page = %+v", page)
+	}
+	doJSON(t, app, http.MethodGet, "/events?since=3", "", http.StatusOK, &page)
+	if len(page.Events) != 0 || page.NextSince != 3 {
+		t.Errorf("empty page = %+v", page)
+	}
+	doJSON(t, app, http.MethodGet, "/events?since=-1", "", http.StatusUnprocessableEntity, nil)
+
+	doJSON(t, newTestApp(t), http.MethodGet, "/events", "", http.StatusNotFound, nil)
+}
+
+func TestRunUnixSocket(t *testing.T) {
+	svc, err := service.NewFiltersService(repository.NewMemoryRepository(), time.UTC, testUserID)
+	if err != nil {
+		t.Fatalf("NewFiltersService: %v", err)
+	}
+	sock := filepath.Join(t.TempDir(), "search-filter.sock")
+	// Оставшийся от прошлого запуска файл не должен мешать.
+	if err := os.WriteFile(sock, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	srv := httpapi.NewServer(&config.Config{HTTPUnixSocket: sock}, svc)
+	go srv.Run()
+	t.Cleanup(func() { srv.Shutdown(context.Background()) })
+
+	client := &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", sock)
+		}},
+	}
+	var resp *http.Response
+	for i := 0; i < 50; i++ {
+		if resp, err = client.Get("http://unix/healthz"); err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("GET /healthz over unix socket: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("GET /healthz = %d, want 204", resp.StatusCode)
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	svc, err := service.NewFiltersService(repository.NewMemoryRepository(), time.UTC, testUserID)
+	if err != nil {
+		t.Fatalf("NewFiltersService: %v", err)
+	}
+	app := httpapi.NewServer(&config.Config{HTTPBodyLimit: 64}, svc).App()
+
+	body := `{"name":"big","query":{"text":"` + strings.Repeat("x", 100) + `"}}`
+	req := httptest.NewRequest(http.MethodPost, "/filters", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	// fasthttp отвечает 413 и закрывает соединение, app.Test видит это как ошибку.
+	resp, err := app.Test(req, -1)
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusRequestEntityTooLarge {
+			t.Errorf("POST oversized body = %d, want 413", resp.StatusCode)
+		}
+	} else if !strings.Contains(err.Error(), "body size exceeds") {
+		t.Errorf("POST oversized body: %v", err)
+	}
+}
+
+// requestIDService запоминает request ID из контекста, с которым вызван List.
+type requestIDService struct {
+	service.Filters
+	got string
+}
+
+func (s *requestIDService) List(ctx context.Context) ([]models.FilterListItem, error) {
+	s.got = logging.RequestID(ctx)
+	return s.Filters.List(ctx)
+}
+
+func TestRequestID(t *testing.T) {
+	inner, err := service.NewFiltersService(repository.NewMemoryRepository(), time.UTC, testUserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := &requestIDService{Filters: inner}
+	app := httpapi.NewServer(&config.Config{}, svc).App()
+
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{name: "from header", header: "req-42.a_b", keep: true},
+		{name: "generated", header: ""},
+		{name: "unsafe replaced", header: "x*/ DROP TABLE filters; /*"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/filters", nil)
+			if tt.header != "" {
+				req.Header.Set(httpapi.HeaderRequestID, tt.header)
+			}
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			id := resp.Header.Get(httpapi.HeaderRequestID)
+			if id == "" || id != svc.got {
+				t.Fatalf("response id %q, service saw %q", id, svc.got)
+			}
+			if (id == tt.header) != tt.keep {
+				t.Errorf("id = %q for header %q", id, tt.header)
+			}
+		})
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	m := metrics.New()
+	repo := repository.NewInstrumentedRepository(repository.NewMemoryRepository(), m.ObserveRepository)
+	svc, err := service.NewFiltersService(repo, time.UTC, testUserID, service.WithRenderObserver(m.ObserveRender))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := httpapi.NewServer(&config.Config{}, svc, httpapi.WithMetrics(m)).App()
+
+	f := createFilter(t, app, "f", `{"tags":["go"]}`)
+	doJSON(t, app, http.MethodGet, "/filters/"+f.ID+"/apply", "", http.StatusOK, nil)
+	doJSON(t, app, http.MethodGet, "/filters/00000000-0000-0000-0000-000000000001", "", http.StatusNotFound, nil)
+
+	status, body := do(t, app, http.MethodGet, "/metrics", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("GET /metrics = %d", status)
+	}
+	for _, want := range []string{
+		`search_filter_http_requests_total{method="POST",operation="post-filters",status="200"} 1`,
+		`search_filter_http_requests_total{method="GET",operation="get-filters-by-id",status="404"} 1`,
+		`search_filter_repository_call_duration_seconds_count{method="Get",result="not_found"} 1`,
+		`search_filter_placeholder_render_duration_seconds_count 1`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics do not contain %s:\n%s", want, body)
+		}
+	}
+}
+
+func TestTracing(t *testing.T) {
+	if _, err := tracing.Setup(context.Background(), tracing.Options{Exporter: tracing.ExporterNone}); err != nil {
+		t.Fatal(err)
+	}
+	rec := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+
+	dbs := storage.MustInitSQLite(filepath.Join(t.TempDir(), "filters.db"), nil)
+	t.Cleanup(func() { dbs.SQL.Close() })
+	inner, err := service.NewFiltersService(repository.NewSQLiteRepository(dbs.Reform), time.UTC, testUserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := httpapi.NewServer(&config.Config{}, service.NewTracedFilters(inner)).App()
+	f := createFilter(t, app, "f", `{"date_to":"{{today}}"}`)
+
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		parent  = "00f067aa0ba902b7"
+	)
+	before := len(rec.Ended())
+	req := httptest.NewRequest(http.MethodGet, "/filters/"+f.ID+"/apply", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+parent+"-01")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("apply = %d", resp.StatusCode)
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range rec.Ended()[before:] {
+		if s.SpanContext().TraceID().String() != traceID {
+			t.Errorf("span %q in trace %s, want %s", s.Name(), s.SpanContext().TraceID(), traceID)
+		}
+		spans[s.Name()] = s
+	}
+	server, ok := spans["GET /filters/:id/apply"]
+	if !ok {
+		t.Fatalf("no server span in %v", slices.Collect(maps.Keys(spans)))
+	}
+	if server.Parent().SpanID().String() != parent {
+		t.Errorf("server span parent = %s, want %s", server.Parent().SpanID(), parent)
+	}
+	for _, name := range []string{"Filters.Apply", "placeholder.RenderQuery", "sql.conn.query"} {
+		if _, ok := spans[name]; !ok {
+			t.Errorf("no %s span in %v", name, slices.Collect(maps.Keys(spans)))
+		}
+	}
+	if apply := spans["Filters.Apply"]; apply != nil && apply.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Errorf("Filters.Apply is not a child of the server span")
+	}
+}
+
+func TestProbes(t *testing.T) {
+	svc, err := service.NewFiltersService(repository.NewMemoryRepository(), time.UTC, testUserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbErr := errors.New("connection refused")
+	h := health.New(time.Second)
+	h.Add("postgres", func(context.Context) error { return dbErr })
+	srv := httpapi.NewServer(&config.Config{}, svc, httpapi.WithHealth(h))
+	app := srv.App()
+
+	var live map[string]string
+	doJSON(t, app, http.MethodGet, "/livez", "", http.StatusOK, &live)
+	if live["status"] != health.StatusOK {
+		t.Errorf("livez = %v", live)
+	}
+
+	var rep health.Report
+	doJSON(t, app, http.MethodGet, "/readyz", "", http.StatusServiceUnavailable, &rep)
+	if got := rep.Checks["postgres"]; got.Status != health.StatusFail || got.Error != dbErr.Error() {
+		t.Errorf("readyz = %+v", rep)
+	}
+
+	dbErr = nil
+	doJSON(t, app, http.MethodGet, "/readyz", "", http.StatusOK, &rep)
+	if !rep.OK() {
+		t.Errorf("readyz = %+v", rep)
+	}
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	doJSON(t, app, http.MethodGet, "/readyz", "", http.StatusServiceUnavailable, &rep)
+	if rep.Status != health.StatusShuttingDown {
+		t.Errorf("readyz after shutdown = %+v", rep)
+	}
+	doJSON(t, app, http.MethodGet, "/livez", "", http.StatusOK, nil)
+}
+
+type blockingService struct {
+	service.Filters
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingService) List(ctx context.Context) ([]models.FilterListItem, error) {
+	close(s.started)
+	<-s.release
+	return s.Filters.List(ctx)
+}
+
+func TestShutdownDrain(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		wantErr bool
+	}{
+		{name: "waits for in-flight request", timeout: 5 * time.Second},
+		{name: "gives up after timeout", timeout: 100 * time.Millisecond, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner, err := service.NewFiltersService(repository.NewMemoryRepository(), time.UTC, testUserID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			svc := &blockingService{Filters: inner, started: make(chan struct{}), release: make(chan struct{})}
+			sock := filepath.Join(t.TempDir(), "search-filter.sock")
+			srv := httpapi.NewServer(&config.Config{HTTPUnixSocket: sock}, svc)
+			go srv.Run()
+
+			client := &http.Client{Transport: &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", sock)
+			}}}
+			for i := 0; i < 50; i++ {
+				if _, err = os.Stat(sock); err == nil {
+					break
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+
+			status := make(chan int, 1)
+			go func() {
+				resp, err := client.Get("http://unix/filters")
+				if err != nil {
+					status <- 0
+					return
+				}
+				resp.Body.Close()
+				status <- resp.StatusCode
+			}()
+			<-svc.started
+			if n := srv.InFlight(); n != 1 {
+				t.Errorf("InFlight = %d, want 1", n)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			shutdownErr := make(chan error, 1)
+			go func() { shutdownErr <- srv.Shutdown(ctx) }()
+			if !tt.wantErr {
+				time.Sleep(50 * time.Millisecond)
+				close(svc.release)
+			}
+
+			err = <-shutdownErr
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "1 requests still in flight") {
+					t.Errorf("Shutdown = %v, want in-flight error", err)
+				}
+				close(svc.release)
+			} else if err != nil {
+				t.Errorf("Shutdown = %v", err)
+			}
+			if got := <-status; got != http.StatusOK {
+				t.Errorf("in-flight request status = %d, want 200", got)
+			}
+		})
+	}
+}
+
+type errService struct {
+	service.Filters
+	err error
+}
+
+func (s *errService) Get(context.Context, uuid.UUID) (*models.Filter, error) {
+	return nil, s.err
+}
+
+func TestProblemResponses(t *testing.T) {
+	const id = "6f1c4b8e-0000-4000-8000-000000000000"
+	tests := []struct {
+		name       string
+		err        error
+		status     int
+		code       string
+		retryAfter string
+		location   string
+	}{
+		{name: "not found", err: service.NotFound(service.CodeFilterNotFound, "filter %s", id), status: http.StatusNotFound, code: "filter_not_found"},
+		{name: "validation", err: service.Invalid(service.CodeInvalidQuery, "bad ref", service.FieldError{Field: "query", Message: "bad ref"}),
+			status: http.StatusUnprocessableEntity, code: "invalid_query", location: "query"},
+		{name: "conflict", err: service.Conflict(service.CodeFilterReferenced, "referenced"), status: http.StatusConflict, code: "filter_referenced"},
+		{name: "forbidden", err: service.Forbidden(service.CodeForbidden, "no access"), status: http.StatusForbidden, code: "forbidden"},
+		{name: "precondition", err: service.PreconditionFailed(service.CodePreconditionFailed, "etag mismatch"), status: http.StatusPreconditionFailed, code: "precondition_failed"},
+		{name: "rate limited", err: service.RateLimited(service.CodeRateLimited, 1500*time.Millisecond, "slow down"),
+			status: http.StatusTooManyRequests, code: "rate_limited", retryAfter: "2"},
+		{name: "wrapped sentinel", err: fmt.Errorf("lookup: %w", service.ErrNoBackend), status: http.StatusNotImplemented, code: "search_backend_not_configured"},
+		{name: "internal", err: errors.New("pq: connection reset"), status: http.StatusInternalServerError, code: "internal_error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := httpapi.NewServer(&config.Config{}, &errService{err: tt.err}).App()
+			req := httptest.NewRequest(http.MethodGet, "/filters/"+id, nil)
+			req.Header.Set(httpapi.HeaderRequestID, "req-1")
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			var p struct {
+				Title    string `json:"title"`
+				Status   int    `json:"status"`
+				Detail   string `json:"detail"`
+				Instance string `json:"instance"`
+				Code     string `json:"code"`
+				Errors   []struct {
+					Location string `json:"location"`
+				} `json:"errors"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status || p.Status != tt.status || p.Code != tt.code {
+				t.Errorf("got %d %+v, want %d code %s", resp.StatusCode, p, tt.status, tt.code)
+			}
+			if ct := resp.Header.Get("Content-Type"); ct != "application/problem+json" {
+				t.Errorf("Content-Type = %s", ct)
+			}
+			if p.Instance != "urn:request:req-1" {
+				t.Errorf("instance = %q", p.Instance)
+			}
+			if got := resp.Header.Get("Retry-After"); got != tt.retryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.retryAfter)
+			}
+			if tt.location != "" && (len(p.Errors) != 1 || p.Errors[0].Location != tt.location) {
+				t.Errorf("errors = %+v, want location %s", p.Errors, tt.location)
+			}
+			if tt.code == "internal_error" && strings.Contains(p.Detail, "pq:") {
+				t.Errorf("internal detail leaked: %q", p.Detail)
+			}
+		})
+	}
+
+	// Ошибки валидации самой huma получают тот же формат.
+	app := newTestApp(t)
+	status, body := do(t, app, http.MethodPost, "/filters", "application/json", `{"name":"","query":{"a":1}}`)
+	if status != http.StatusUnprocessableEntity || !strings.Contains(string(body), `"code":"validation_failed"`) ||
+		!strings.Contains(string(body), `"instance":"urn:request:`) {
+		t.Errorf("huma validation = %d %s", status, body)
+	}
+}
@@ -2,14 +2,27 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"search-filter/pkg/cache"
 	"search-filter/pkg/config"
+	"search-filter/pkg/health"
+	"search-filter/pkg/metrics"
 	"search-filter/pkg/service"
+	"search-filter/pkg/webhook"
 
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/danielgtaylor/huma/v2/adapters/humafiber"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 )
 
 type Server struct {
@@ -17,36 +30,198 @@ type Server struct {
 	api     huma.API
 	service service.Filters
 	cfg     *config.Config
+	cache   *cache.Cache
+	events  service.Events
+	webhook *webhook.Dispatcher
+	metrics *metrics.Metrics
+	health  *health.Checker
+
+	// inFlight — число обрабатываемых запросов, включая открытые SSE-потоки.
+	inFlight atomic.Int64
+
+	hub *streamHub
+	// streamCtx отменяется в Shutdown и завершает открытые SSE-потоки, иначе
+	// fiber ждал бы их закрытия клиентами.
+	streamCtx  context.Context
+	stopStream context.CancelFunc
+}
+
+type Option func(*Server)
+
+// WithHealth задаёт проверки для GET /readyz; без него /readyz проверяет только
+// то, что сервер не останавливается.
+func WithHealth(h *health.Checker) Option {
+	return func(s *Server) { s.health = h }
+}
+
+// WithCache публикует статистику кэша на GET /cache/stats.
+func WithCache(c *cache.Cache) Option {
+	return func(s *Server) { s.cache = c }
 }
 
-func NewServer(cfg *config.Config, svc service.Filters) *Server {
+// WithEvents публикует ленту событий на GET /events.
+func WithEvents(svc service.Events) Option {
+	return func(s *Server) { s.events = svc }
+}
+
+// WithWebhooks публикует dead-letter доставок вебхуков.
+func WithWebhooks(d *webhook.Dispatcher) Option {
+	return func(s *Server) { s.webhook = d }
+}
+
+// WithMetrics публикует метрики Prometheus на GET /metrics и считает запросы
+// по операциям huma.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(s *Server) { s.metrics = m }
+}
+
+func NewServer(cfg *config.Config, svc service.Filters, opts ...Option) *Server {
 	app := fiber.New(fiber.Config{
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		ReadTimeout:  cfg.HTTPReadTimeout,
+		WriteTimeout: cfg.HTTPWriteTimeout,
+		IdleTimeout:  cfg.HTTPIdleTimeout,
+		BodyLimit:    cfg.HTTPBodyLimit,
 	})
+	s := &Server{app: app, service: svc, cfg: cfg, hub: newStreamHub()}
+	s.streamCtx, s.stopStream = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(s)
+	}
+	app.Use(s.countInFlight, requestLogger, traceRequests)
 
 	hcfg := huma.DefaultConfig("application/json", "utf-8")
 	hcfg.Info.Title = "Search Filters API"
 	hcfg.Info.Version = "1.0.0"
 
-	api := humafiber.New(app, hcfg)
+	api := humafiber.NewWithGroup(app, verbRouter{app}, hcfg)
+	s.api = api
 
 	app.Get("/healthz", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) })
+	if s.health == nil {
+		s.health = health.New(cfg.HealthCheckTimeout)
+	}
+	app.Get("/livez", livez)
+	app.Get("/readyz", s.readyz)
+
+	if s.metrics != nil {
+		app.Get("/metrics", adaptor.HTTPHandler(s.metrics.Handler()))
+		s.metrics.RegisterInFlight(s.InFlight)
+		// Middleware huma применяются при регистрации операций, поэтому до RegisterRoutes.
+		api.UseMiddleware(func(ctx huma.Context, next func(huma.Context)) {
+			start := time.Now()
+			next(ctx)
+			op := ctx.Operation()
+			s.metrics.ObserveHTTP(op.OperationID, op.Method, ctx.Status(), time.Since(start))
+		})
+	}
 
-	RegisterRoutes(api, svc)
+	// Регистрируется раньше /filters/{id}, иначе fiber сопоставит stream с :id.
+	if s.events != nil {
+		app.Get("/filters/stream", s.streamFilters)
+	}
 
-	return &Server{app: app, api: api, service: svc, cfg: cfg}
+	RegisterRoutes(api, svc, cfg.ElasticsearchMapping())
+	if s.cache != nil {
+		RegisterCacheRoutes(api, s.cache)
+	}
+	if s.events != nil {
+		RegisterEventRoutes(api, s.events)
+	}
+	if s.webhook != nil {
+		RegisterWebhookRoutes(api, s.webhook)
+	}
+
+	return s
 }
 
 func (s *Server) App() *fiber.App {
 	return s.app
 }
 
-func (s *Server) Run(addr string) error {
-	return s.app.Listen(addr)
+// Run слушает http_unix_socket, если он задан, иначе http_addr; при заданных
+// http_tls_cert/http_tls_key — по TLS с подхватом обновлённых сертификатов.
+func (s *Server) Run() error {
+	ln, err := s.listen()
+	if err != nil {
+		return err
+	}
+	return s.app.Listener(ln)
+}
+
+func (s *Server) listen() (net.Listener, error) {
+	var (
+		ln  net.Listener
+		err error
+	)
+	if path := s.cfg.HTTPUnixSocket; path != "" {
+		// Сокет от предыдущего запуска мешает bind.
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("remove stale socket: %w", err)
+		}
+		ln, err = net.Listen("unix", path)
+	} else {
+		ln, err = net.Listen("tcp", s.cfg.HTTPAddr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if s.cfg.HTTPTLSCert == "" {
+		return ln, nil
+	}
+	certs, err := newCertReloader(s.cfg.HTTPTLSCert, s.cfg.HTTPTLSKey)
+	if err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return tls.NewListener(ln, &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certs.GetCertificate,
+	}), nil
+}
+
+// InFlight возвращает число обрабатываемых запросов.
+func (s *Server) InFlight() int64 {
+	return s.inFlight.Load()
+}
+
+func (s *Server) countInFlight(c *fiber.Ctx) error {
+	s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+	return c.Next()
 }
 
+// Shutdown снимает готовность (/readyz отвечает 503), закрывает SSE-потоки,
+// перестаёт принимать соединения и ждёт завершения обрабатываемых запросов,
+// но не дольше срока ctx. Запросы, не успевшие завершиться, продолжают работать,
+// а Shutdown возвращает ошибку с их числом.
 func (s *Server) Shutdown(ctx context.Context) error {
-	return s.app.ShutdownWithContext(ctx)
+	s.health.Shutdown()
+	s.stopStream()
+	if err := s.app.ShutdownWithContext(ctx); err != nil {
+		return fmt.Errorf("%d requests still in flight: %w", s.InFlight(), err)
+	}
+	return nil
+}
+
+// verbRouter экранирует двоеточия пользовательских методов (/filters:batch),
+// иначе fiber принимает их за начало параметра пути.
+type verbRouter struct {
+	fiber.Router
+}
+
+func (r verbRouter) Add(method, path string, handlers ...fiber.Handler) fiber.Router {
+	return r.Router.Add(method, escapeCustomVerbs(path), handlers...)
+}
+
+func escapeCustomVerbs(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == ':' && i > 0 && path[i-1] != '/' {
+			b.WriteString(`\:`)
+			continue
+		}
+		b.WriteByte(path[i])
+	}
+	return b.String()
 }
@@ -0,0 +1,177 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"search-filter/pkg/logging"
+	"search-filter/pkg/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	streamPage = 100
+	// streamPoll — страховочный опрос на случай пропущенного уведомления и событий,
+	// которые ещё не стали видимы (см. repository.EventLog.Events).
+	streamPoll      = 2 * time.Second
+	streamHeartbeat = 15 * time.Second
+	streamRetry     = 3 * time.Second
+	// streamWriteTimeout ограничивает одну запись в поток.
+	streamWriteTimeout = 10 * time.Second
+)
+
+// streamHub будит SSE-подписчиков, когда в ленте могли появиться события.
+type streamHub struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+func newStreamHub() *streamHub {
+	return &streamHub{ch: make(chan struct{})}
+}
+
+// wait возвращает канал, который закроется при следующем wake.
+func (h *streamHub) wait() <-chan struct{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.ch
+}
+
+func (h *streamHub) wake() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	close(h.ch)
+	h.ch = make(chan struct{})
+}
+
+// NotifyEvents сообщает SSE-подписчикам, что в ленте могли появиться события.
+// Вызывается по NOTIFY filters_changed, поэтому изменения, сделанные на других
+// репликах, доходят до подписчиков этой без ожидания опроса.
+func (s *Server) NotifyEvents() {
+	s.hub.wake()
+}
+
+// streamFilters — GET /filters/stream: лента событий в формате Server-Sent Events.
+// id каждого сообщения — id события outbox, общий для всех реплик, поэтому
+// переподключение с Last-Event-ID (или ?last_event_id=) к любой реплике
+// продолжает ленту без пропусков. Без них поток начинается с текущего момента.
+func (s *Server) streamFilters(c *fiber.Ctx) error {
+	since, err := s.streamStart(c)
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	// Поток пишется после возврата из обработчика, когда c уже переиспользован:
+	// внутрь передаются только значения.
+	conn := c.Context().Conn()
+	ctx := logging.WithRequestID(s.streamCtx, logging.RequestID(c.UserContext()))
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		// Поток пишется уже после возврата из обработчика; считаем его отдельно.
+		s.inFlight.Add(1)
+		defer s.inFlight.Add(-1)
+		streamEvents(ctx, &deadlineWriter{Writer: w, conn: conn}, s.events, s.hub, since)
+	})
+	return nil
+}
+
+func (s *Server) streamStart(c *fiber.Ctx) (int64, error) {
+	raw := c.Get("Last-Event-ID")
+	if raw == "" {
+		raw = c.Query("last_event_id")
+	}
+	if raw == "" {
+		id, err := s.events.Latest(c.UserContext())
+		if err != nil {
+			slog.ErrorContext(c.UserContext(), "stream: latest event", "error", err)
+			return 0, fiber.NewError(fiber.StatusInternalServerError, "internal error")
+		}
+		return id, nil
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id < 0 {
+		return 0, fiber.NewError(fiber.StatusBadRequest, "Last-Event-ID must be a non-negative event id")
+	}
+	return id, nil
+}
+
+// deadlineWriter продлевает срок записи перед каждым Flush: fasthttp выставляет
+// WriteTimeout на весь ответ, и без продления поток обрывался бы через него.
+type deadlineWriter struct {
+	*bufio.Writer
+	conn net.Conn
+}
+
+func (w *deadlineWriter) Flush() error {
+	if err := w.conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil {
+		return err
+	}
+	return w.Writer.Flush()
+}
+
+func streamEvents(ctx context.Context, w *deadlineWriter, events service.Events, hub *streamHub, since int64) {
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds())
+	if err := w.Flush(); err != nil {
+		return
+	}
+
+	poll := time.NewTicker(streamPoll)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		// Канал берётся до чтения ленты, чтобы не пропустить wake между ними.
+		wake := hub.wait()
+
+		evs, err := events.List(ctx, since, streamPage)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			slog.ErrorContext(ctx, "stream: list events", "error", err)
+		}
+		for _, ev := range evs {
+			data, err := json.Marshal(ev)
+			if err != nil {
+				slog.ErrorContext(ctx, "stream: marshal event", "event_id", ev.ID, "error", err)
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data)
+			since = ev.ID
+		}
+		if len(evs) > 0 {
+			// Ошибка записи — клиент отключился.
+			if err := w.Flush(); err != nil {
+				return
+			}
+			if len(evs) == streamPage {
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-wake:
+		case <-poll.C:
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
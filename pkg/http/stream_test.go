@@ -0,0 +1,123 @@
+package http_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"search-filter/pkg/config"
+	httpapi "search-filter/pkg/http"
+	"search-filter/pkg/models"
+	"search-filter/pkg/repository"
+	"search-filter/pkg/service"
+)
+
+// growingLog — лента событий, в которую тест дописывает события на ходу.
+type growingLog struct {
+	mu     sync.Mutex
+	events fakeEventLog
+}
+
+func (l *growingLog) add(ev models.Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, ev)
+}
+
+func (l *growingLog) Events(ctx context.Context, since int64, limit int) ([]models.Event, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.events.Events(ctx, since, limit)
+}
+
+func (l *growingLog) LastEventID(ctx context.Context) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.events.LastEventID(ctx)
+}
+
+func TestStreamFilters(t *testing.T) {
+	el := &growingLog{events: fakeEventLog{
+		{ID: 1, Type: models.EventFilterCreated},
+		{ID: 2, Type: models.EventFilterUpdated},
+	}}
+	svc, err := service.NewFiltersService(repository.NewMemoryRepository(), time.UTC, testUserID)
+	if err != nil {
+		t.Fatalf("NewFiltersService: %v", err)
+	}
+	events, err := service.NewEventsService(el)
+	if err != nil {
+		t.Fatalf("NewEventsService: %v", err)
+	}
+	srv := httpapi.NewServer(&config.Config{}, svc, httpapi.WithEvents(events))
+	// Поток живёт дольше WriteTimeout сервера.
+	srv.App().Server().WriteTimeout = 100 * time.Millisecond
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go srv.App().Listener(ln)
+	t.Cleanup(func() { srv.Shutdown(context.Background()) })
+	base := "http://" + ln.Addr().String()
+
+	open := func(lastEventID string) (*http.Response, *bufio.Reader) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, base+"/filters/stream", nil)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := (&http.Client{Timeout: 5 * time.Second}).Do(req)
+		if err != nil {
+			t.Fatalf("GET /filters/stream: %v", err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp, bufio.NewReader(resp.Body)
+	}
+	nextID := func(r *bufio.Reader) string {
+		t.Helper()
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatalf("read stream: %v", err)
+			}
+			if id, ok := strings.CutPrefix(strings.TrimSpace(line), "id: "); ok {
+				return id
+			}
+		}
+	}
+
+	resp, r := open("1")
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if id := nextID(r); id != "2" {
+		t.Fatalf("resumed stream starts at %s, want 2", id)
+	}
+
+	_, live := open("")
+	time.Sleep(300 * time.Millisecond)
+	el.add(models.Event{ID: 3, Type: models.EventFilterDeleted})
+	srv.NotifyEvents()
+	if id := nextID(r); id != "3" {
+		t.Errorf("resumed stream next = %s, want 3", id)
+	}
+	if id := nextID(live); id != "3" {
+		t.Errorf("live stream first = %s, want 3", id)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, base+"/filters/stream?last_event_id=abc", nil)
+	bad, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	bad.Body.Close()
+	if bad.StatusCode != http.StatusBadRequest {
+		t.Errorf("bad last_event_id = %d, want 400", bad.StatusCode)
+	}
+}
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// certCheckInterval — как часто при рукопожатиях проверять, не обновились ли файлы.
+const certCheckInterval = 5 * time.Second
+
+// certReloader отдаёт сертификат для tls.Config.GetCertificate и перечитывает его,
+// когда меняются файлы (cert-manager, certbot), — без перезапуска сервера.
+// Если новые файлы не читаются, продолжает отдавать прежний сертификат.
+type certReloader struct {
+	certPath, keyPath string
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	certMod   time.Time
+	keyMod    time.Time
+	checkedAt time.Time
+	now       func() time.Time
+}
+
+func newCertReloader(certPath, keyPath string) (*certReloader, error) {
+	r := &certReloader{certPath: certPath, keyPath: keyPath, now: time.Now}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) load() error {
+	certInfo, err := os.Stat(r.certPath)
+	if err != nil {
+		return fmt.Errorf("tls cert: %w", err)
+	}
+	keyInfo, err := os.Stat(r.keyPath)
+	if err != nil {
+		return fmt.Errorf("tls key: %w", err)
+	}
+	cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		return fmt.Errorf("tls key pair: %w", err)
+	}
+	r.cert, r.certMod, r.keyMod = &cert, certInfo.ModTime(), keyInfo.ModTime()
+	return nil
+}
+
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now := r.now(); now.Sub(r.checkedAt) >= certCheckInterval {
+		r.checkedAt = now
+		if r.changed() {
+			if err := r.load(); err != nil {
+				slog.Error("tls reload failed, keeping previous certificate", "error", err)
+			} else {
+				slog.Info("tls certificate reloaded", "cert", r.certPath)
+			}
+		}
+	}
+	return r.cert, nil
+}
+
+func (r *certReloader) changed() bool {
+	certInfo, err := os.Stat(r.certPath)
+	if err != nil {
+		return false
+	}
+	keyInfo, err := os.Stat(r.keyPath)
+	if err != nil {
+		return false
+	}
+	return !certInfo.ModTime().Equal(r.certMod) || !keyInfo.ModTime().Equal(r.keyMod)
+}
@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestRequestIDAttr(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, FormatJSON, "info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	logger.With("component", "test").InfoContext(ctx, "hello")
+	logger.DebugContext(ctx, "hidden")
+	logger.Info("no context")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("want 2 records, got %d: %s", len(lines), buf.String())
+	}
+	var rec map[string]any
+	if err := json.Unmarshal(lines[0], &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec["request_id"] != "req-1" || rec["component"] != "test" {
+		t.Errorf("record = %v", rec)
+	}
+	var plain map[string]any
+	if err := json.Unmarshal(lines[1], &plain); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := plain["request_id"]; ok {
+		t.Errorf("record without context has request_id: %v", plain)
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "xml", "info"); err == nil {
+		t.Error("want error for unknown format")
+	}
+	if _, err := New(&bytes.Buffer{}, FormatJSON, "verbose"); err == nil {
+		t.Error("want error for unknown level")
+	}
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"search-filter/pkg/cache"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// cacheCollector читает cache.Stats при каждом сборе метрик.
+type cacheCollector struct {
+	cache         *cache.Cache
+	hits          *prometheus.Desc
+	misses        *prometheus.Desc
+	hitRatio      *prometheus.Desc
+	invalidations *prometheus.Desc
+	errors        *prometheus.Desc
+	entries       *prometheus.Desc
+}
+
+func newCacheCollector(c *cache.Cache) *cacheCollector {
+	name := func(n string) string { return prometheus.BuildFQName(namespace, "cache", n) }
+	return &cacheCollector{
+		cache:         c,
+		hits:          prometheus.NewDesc(name("hits_total"), "Cache hits by key kind.", []string{"kind"}, nil),
+		misses:        prometheus.NewDesc(name("misses_total"), "Cache misses by key kind.", []string{"kind"}, nil),
+		hitRatio:      prometheus.NewDesc(name("hit_ratio"), "Cache hit ratio by key kind since start.", []string{"kind"}, nil),
+		invalidations: prometheus.NewDesc(name("invalidations_total"), "Full cache invalidations.", nil, nil),
+		errors:        prometheus.NewDesc(name("errors_total"), "Cache store errors.", nil, nil),
+		entries:       prometheus.NewDesc(name("entries"), "Entries in the cache store, if it reports them.", nil, nil),
+	}
+}
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.hitRatio
+	ch <- c.invalidations
+	ch <- c.errors
+	ch <- c.entries
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.cache.Stats()
+	for kind, k := range s.Kinds {
+		ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(k.Hits), kind)
+		ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(k.Misses), kind)
+		ch <- prometheus.MustNewConstMetric(c.hitRatio, prometheus.GaugeValue, k.HitRatio, kind)
+	}
+	ch <- prometheus.MustNewConstMetric(c.invalidations, prometheus.CounterValue, float64(s.Invalidations))
+	ch <- prometheus.MustNewConstMetric(c.errors, prometheus.CounterValue, float64(s.Errors))
+	if s.Entries >= 0 {
+		ch <- prometheus.MustNewConstMetric(c.entries, prometheus.GaugeValue, float64(s.Entries))
+	}
+}
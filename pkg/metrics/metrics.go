@@ -0,0 +1,135 @@
+// Package metrics собирает метрики Prometheus сервиса в собственном реестре:
+// HTTP-запросы по операциям huma, пул соединений БД, вызовы репозитория,
+// рендеринг плейсхолдеров и кэш.
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"search-filter/pkg/cache"
+	"search-filter/pkg/placeholder"
+	"search-filter/pkg/repository"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "search_filter"
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests   *prometheus.CounterVec
+	httpDuration   *prometheus.HistogramVec
+	repoDuration   *prometheus.HistogramVec
+	renderDuration prometheus.Histogram
+	renderFailures *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by huma operation, method and status code.",
+		}, []string{"operation", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by huma operation and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation", "method"}),
+		repoDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "repository_call_duration_seconds",
+			Help:      "Repository call latency by method and result (ok, not_found, error).",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"method", "result"}),
+		renderDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "placeholder_render_duration_seconds",
+			Help:      "Placeholder rendering latency in apply.",
+			Buckets:   []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05},
+		}),
+		renderFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "placeholder_render_failures_total",
+			Help:      "Placeholder rendering failures by reason.",
+		}, []string{"reason"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpDuration, m.repoDuration, m.renderDuration, m.renderFailures,
+	)
+	return m
+}
+
+// Handler отдаёт метрики в формате Prometheus.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RegisterDB публикует статистику пула sql.DB (go_sql_* с меткой db_name).
+func (m *Metrics) RegisterDB(db *sql.DB, name string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterInFlight публикует число HTTP-запросов, обрабатываемых в данный момент.
+func (m *Metrics) RegisterInFlight(inFlight func() int64) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests currently being served, including open SSE streams.",
+	}, func() float64 { return float64(inFlight()) }))
+}
+
+// RegisterCache публикует попадания, промахи и долю попаданий кэша по видам ключей.
+func (m *Metrics) RegisterCache(c *cache.Cache) {
+	m.registry.MustRegister(newCacheCollector(c))
+}
+
+func (m *Metrics) ObserveHTTP(operation, method string, status int, d time.Duration) {
+	m.httpRequests.WithLabelValues(operation, method, strconv.Itoa(status)).Inc()
+	m.httpDuration.WithLabelValues(operation, method).Observe(d.Seconds())
+}
+
+// ObserveRepository подходит как repository.Observer.
+func (m *Metrics) ObserveRepository(method string, d time.Duration, err error) {
+	result := "ok"
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		result = "not_found"
+	case err != nil:
+		result = "error"
+	}
+	m.repoDuration.WithLabelValues(method, result).Observe(d.Seconds())
+}
+
+// ObserveRender подходит как service.RenderObserver.
+func (m *Metrics) ObserveRender(d time.Duration, err error) {
+	m.renderDuration.Observe(d.Seconds())
+	if err != nil {
+		m.renderFailures.WithLabelValues(renderReason(err)).Inc()
+	}
+}
+
+func renderReason(err error) string {
+	switch {
+	case errors.Is(err, placeholder.ErrParse):
+		return "parse"
+	case errors.Is(err, placeholder.ErrExecute):
+		return "execute"
+	case errors.Is(err, placeholder.ErrMarshal):
+		return "marshal"
+	case errors.Is(err, placeholder.ErrResult):
+		return "invalid_result"
+	}
+	return "other"
+}
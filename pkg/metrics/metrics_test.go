@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"search-filter/pkg/cache"
+	"search-filter/pkg/placeholder"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRenderFailures(t *testing.T) {
+	m := New()
+	_, err := placeholder.RenderTemplate("{{ unknown }}", time.Now(), time.UTC, 1)
+	m.ObserveRender(time.Millisecond, err)
+	m.ObserveRender(time.Millisecond, nil)
+
+	if got := testutil.ToFloat64(m.renderFailures.WithLabelValues("parse")); got != 1 {
+		t.Errorf("parse failures = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(m.renderDuration); got != 1 {
+		t.Errorf("render histogram series = %d", got)
+	}
+}
+
+func TestCacheCollector(t *testing.T) {
+	ctx := context.Background()
+	c := cache.New(cache.NewLRU(10), "test:", time.Minute)
+	var v string
+	gen, _ := c.Get(ctx, "filter:1", &v)
+	c.Set(ctx, gen, "filter:1", "x")
+	c.Get(ctx, "filter:1", &v)
+	c.Get(ctx, "filter:1", &v)
+
+	want := `
+# HELP search_filter_cache_hit_ratio Cache hit ratio by key kind since start.
+# TYPE search_filter_cache_hit_ratio gauge
+search_filter_cache_hit_ratio{kind="filter"} 0.6666666666666666
+# HELP search_filter_cache_hits_total Cache hits by key kind.
+# TYPE search_filter_cache_hits_total counter
+search_filter_cache_hits_total{kind="filter"} 2
+# HELP search_filter_cache_misses_total Cache misses by key kind.
+# TYPE search_filter_cache_misses_total counter
+search_filter_cache_misses_total{kind="filter"} 1
+`
+	err := testutil.CollectAndCompare(newCacheCollector(c), strings.NewReader(want),
+		"search_filter_cache_hit_ratio", "search_filter_cache_hits_total", "search_filter_cache_misses_total")
+	if err != nil {
+		t.Error(err)
+	}
+}
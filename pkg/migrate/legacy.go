@@ -0,0 +1,16 @@
+package migrate
+
+import (
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// LegacyNamespace — пространство имён UUID v3 для бывших BIGINT id фильтров.
+var LegacyNamespace = uuid.MustParse("0b0266d7-9303-43ed-9bf5-3cb5285036da")
+
+// LegacyFilterID возвращает UUID, который миграция 20250901170100 присваивает
+// фильтру со старым BIGINT id. Совпадает с SQL-функцией filter_legacy_uuid.
+func LegacyFilterID(oldID int64) uuid.UUID {
+	return uuid.NewMD5(LegacyNamespace, []byte(strconv.FormatInt(oldID, 10)))
+}
@@ -0,0 +1,77 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+
+	"search-filter/migrations"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+// Ожидаемые значения — UUID v3 по RFC 4122, посчитанные независимо от Go
+// (uuid.uuid3 в Python).
+var legacyVectors = map[int64]string{
+	1:                   "cfeec1b7-40a6-3043-bbc6-f693e00532e8",
+	42:                  "d68e77f4-711f-3454-972b-33e62b01188d",
+	9223372036854775807: "7e7f4221-7679-35b5-a275-17185a91f261",
+}
+
+func TestLegacyFilterID(t *testing.T) {
+	for id, want := range legacyVectors {
+		if got := LegacyFilterID(id); got.String() != want {
+			t.Errorf("LegacyFilterID(%d) = %s, want %s", id, got, want)
+		}
+	}
+}
+
+// TestLegacyFilterIDMatchesSQL сверяет LegacyFilterID с SQL-функцией
+// filter_legacy_uuid из миграции. Запускается против Postgres из
+// SEARCHFILTER_TEST_POSTGRES_DSN; функция создаётся в транзакции и откатывается.
+func TestLegacyFilterIDMatchesSQL(t *testing.T) {
+	dsn := os.Getenv("SEARCHFILTER_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("SEARCHFILTER_TEST_POSTGRES_DSN is not set")
+	}
+
+	raw, err := fs.ReadFile(migrations.FS, "20250901170100_assign_legacy_filter_uuids.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, rest, ok := strings.Cut(string(raw), "-- +goose StatementBegin")
+	fn, _, ok2 := strings.Cut(rest, "-- +goose StatementEnd")
+	if !ok || !ok2 || !strings.Contains(fn, "FUNCTION filter_legacy_uuid") {
+		t.Fatal("filter_legacy_uuid not found in the migration")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, fn); err != nil {
+		t.Fatalf("create function: %v", err)
+	}
+
+	for id := range legacyVectors {
+		var got uuid.UUID
+		if err := tx.QueryRowContext(ctx, "SELECT filter_legacy_uuid($1)", id).Scan(&got); err != nil {
+			t.Fatalf("filter_legacy_uuid(%d): %v", id, err)
+		}
+		if want := LegacyFilterID(id); got != want {
+			t.Errorf("filter_legacy_uuid(%d) = %s, LegacyFilterID = %s", id, got, want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"search-filter/migrations"
+
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+// Source возвращает файловую систему с миграциями: встроенную, если dir пуст,
+// иначе каталог на диске.
+func Source(dir string) fs.FS {
+	if dir == "" {
+		return migrations.FS
+	}
+	return os.DirFS(dir)
+}
+
+// NewProvider разрешает миграции вне очереди: 20250901165000 добавлена позже
+// 20250901170000 и должна применяться и в базах, где более поздние уже есть.
+func NewProvider(db *sql.DB, dir string, opts ...goose.ProviderOption) (*goose.Provider, error) {
+	opts = append([]goose.ProviderOption{goose.WithAllowOutofOrder(true)}, opts...)
+	return goose.NewProvider(goose.DialectPostgres, db, Source(dir), opts...)
+}
+
+var (
+	ErrSchemaAhead = errors.New("database schema is newer than this binary")
+	ErrPending     = errors.New("database has pending migrations")
+)
+
+// Up применяет встроенные миграции под advisory lock Postgres, чтобы несколько
+// реплик, стартующих одновременно, не применяли их параллельно. Если в БД уже
+// есть версия новее известных бинарнику, возвращает ErrSchemaAhead. Версия
+// проверяется под той же блокировкой, поэтому реплика нового бинарника не может
+// применить миграции между проверкой и Up. Блокировка держится на отдельном
+// соединении, так что пулу db нужно не меньше двух соединений.
+func Up(ctx context.Context, db *sql.DB) (res []*goose.MigrationResult, err error) {
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, err
+	}
+	p, err := NewProvider(db, "")
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := locker.SessionLock(ctx, conn); err != nil {
+		return nil, fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		if uerr := locker.SessionUnlock(context.WithoutCancel(ctx), conn); uerr != nil && err == nil {
+			err = fmt.Errorf("release migration lock: %w", uerr)
+		}
+	}()
+
+	current, target, err := p.GetVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if current > target {
+		return nil, fmt.Errorf("%w: database at %d, binary knows up to %d", ErrSchemaAhead, current, target)
+	}
+	return p.Up(ctx)
+}
+
+// Checker сверяет версию схемы в БД со встроенными миграциями для проб
+// готовности. Провайдер создаётся один раз: разбор миграций не повторяется на
+// каждую пробу.
+type Checker struct {
+	p *goose.Provider
+}
+
+func NewChecker(db *sql.DB) (*Checker, error) {
+	p, err := NewProvider(db, "")
+	if err != nil {
+		return nil, err
+	}
+	return &Checker{p: p}, nil
+}
+
+// Check возвращает ErrPending, если часть миграций не применена (в том числе
+// вне очереди), и ErrSchemaAhead, если БД новее бинарника.
+func (c *Checker) Check(ctx context.Context) error {
+	current, target, err := c.p.GetVersions(ctx)
+	if err != nil {
+		return err
+	}
+	if current > target {
+		return fmt.Errorf("%w: database at %d, binary knows up to %d", ErrSchemaAhead, current, target)
+	}
+	pending, err := c.p.HasPending(ctx)
+	if err != nil {
+		return err
+	}
+	if pending {
+		return fmt.Errorf("%w: database at %d, binary expects %d", ErrPending, current, target)
+	}
+	return nil
+}
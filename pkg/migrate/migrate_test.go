@@ -0,0 +1,151 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestChecker запускается против Postgres из SEARCHFILTER_TEST_POSTGRES_DSN.
+func TestChecker(t *testing.T) {
+	dsn := os.Getenv("SEARCHFILTER_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("SEARCHFILTER_TEST_POSTGRES_DSN is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	ctx := context.Background()
+	if _, err := Up(ctx, db); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	c, err := NewChecker(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Check(ctx); err != nil {
+		t.Fatalf("Check after Up: %v", err)
+	}
+
+	// Версия, которой нет в бинарнике, — как после выкатки более новой реплики.
+	const future = 99991231000000
+	if _, err := db.ExecContext(ctx, "INSERT INTO goose_db_version (version_id, is_applied) VALUES ($1, true)", future); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_, _ = db.ExecContext(context.Background(), "DELETE FROM goose_db_version WHERE version_id = $1", future)
+	})
+	if err := c.Check(ctx); !errors.Is(err, ErrSchemaAhead) {
+		t.Errorf("Check with a newer schema = %v, want ErrSchemaAhead", err)
+	}
+}
+
+// TestRollbackKeepsLegacyIDs проверяет, что откат перехода на UUID возвращает
+// прежние BIGINT id, а повторный up — те же UUID. Запускается против Postgres из
+// SEARCHFILTER_TEST_POSTGRES_DSN в отдельной схеме, которая удаляется после теста.
+func TestRollbackKeepsLegacyIDs(t *testing.T) {
+	dsn := os.Getenv("SEARCHFILTER_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("SEARCHFILTER_TEST_POSTGRES_DSN is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	// Одно соединение, чтобы search_path действовал и на goose, и на проверки.
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	schema := fmt.Sprintf("rollback_test_%d", time.Now().UnixNano())
+	if _, err := db.ExecContext(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_, _ = db.ExecContext(context.Background(), "DROP SCHEMA "+schema+" CASCADE")
+	})
+	if _, err := db.ExecContext(ctx, "SET search_path TO "+schema); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewProvider(db, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.UpTo(ctx, 20250827222034); err != nil {
+		t.Fatalf("UpTo: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO filters (name) VALUES ('a'), ('b'), ('c')"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "DELETE FROM filters WHERE id = 2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	assertIDs(t, db, "SELECT id::text FROM filters", LegacyFilterID(1).String(), LegacyFilterID(3).String())
+	// Фильтр, созданный уже с UUID, и зависимость между фильтрами.
+	if _, err := db.ExecContext(ctx, "INSERT INTO filters (name) VALUES ('d')"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO filter_dependencies (filter_id, depends_on) VALUES ($1, $2)",
+		LegacyFilterID(3), LegacyFilterID(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.DownTo(ctx, 20250901165000); err != nil {
+		t.Fatalf("DownTo: %v", err)
+	}
+	assertIDs(t, db, "SELECT id::text FROM filters", "1", "3", "4")
+	assertIDs(t, db, "SELECT id::text FROM filters WHERE name = 'd'", "4")
+	if _, err := db.ExecContext(ctx, "INSERT INTO filters (name) VALUES ('e')"); err != nil {
+		t.Fatalf("insert after down: %v", err)
+	}
+	assertIDs(t, db, "SELECT id::text FROM filters WHERE name = 'e'", "5")
+
+	if _, err := p.Up(ctx); err != nil {
+		t.Fatalf("Up after down: %v", err)
+	}
+	want := make([]string, 0, 4)
+	for _, id := range []int64{1, 3, 4, 5} {
+		want = append(want, LegacyFilterID(id).String())
+	}
+	assertIDs(t, db, "SELECT id::text FROM filters", want...)
+	assertIDs(t, db, "SELECT old_id::text FROM filter_id_map", "1", "3", "4", "5")
+}
+
+// assertIDs сравнивает значения первого столбца запроса с want без учёта порядка.
+func assertIDs(t *testing.T, db *sql.DB, query string, want ...string) {
+	t.Helper()
+	rows, err := db.Query(query)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, s)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(got)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Errorf("%s = %v, want %v", query, got, want)
+	}
+}
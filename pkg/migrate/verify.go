@@ -0,0 +1,165 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/database"
+)
+
+// Issue описывает потерю данных, к которой приведёт откат миграции.
+type Issue struct {
+	Version int64
+	Path    string
+	Reason  string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%d (%s): %s", i.Version, i.Path, i.Reason)
+}
+
+// downCheck возвращает описание потерь при откате миграции или "", если откат безопасен.
+type downCheck func(ctx context.Context, db *sql.DB) (string, error)
+
+var downChecks = map[int64]downCheck{
+	20250827222034: func(ctx context.Context, db *sql.DB) (string, error) {
+		n, err := count(ctx, db, "SELECT count(*) FROM filters")
+		if err != nil || n == 0 {
+			return "", err
+		}
+		return fmt.Sprintf("таблица filters будет удалена вместе с %d фильтрами", n), nil
+	},
+	// Откат 20250901170100 и 20250901170000 восстанавливает прежние BIGINT id, а
+	// повторный up — те же UUID. Теряются только UUID фильтров, созданных после
+	// перехода: у них нет прежнего id, и они получат новые.
+	20250901170000: func(ctx context.Context, db *sql.DB) (string, error) {
+		query := "SELECT count(*) FROM filters AS f WHERE true"
+		hasMap, err := exists(ctx, db, "SELECT to_regclass('filter_id_map') IS NOT NULL")
+		if err != nil {
+			return "", err
+		}
+		if hasMap {
+			query += " AND NOT EXISTS (SELECT 1 FROM filter_id_map AS m WHERE m.new_id = f.id)"
+		}
+		hasLegacy, err := exists(ctx, db, `SELECT EXISTS (SELECT 1 FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = 'filters' AND column_name = 'legacy_id')`)
+		if err != nil {
+			return "", err
+		}
+		if hasLegacy {
+			query += " AND f.legacy_id IS NULL"
+		}
+		n, err := count(ctx, db, query)
+		if err != nil || n == 0 {
+			return "", err
+		}
+		return fmt.Sprintf("%d фильтров без прежнего BIGINT id получат новые id, повторный up не вернёт им прежние UUID", n), nil
+	},
+	20261019100000: func(ctx context.Context, db *sql.DB) (string, error) {
+		n, err := count(ctx, db, "SELECT count(*) FROM filter_dependencies")
+		if err != nil || n == 0 {
+			return "", err
+		}
+		return fmt.Sprintf("будут удалены %d зависимостей между фильтрами; повторный up их не восстановит", n), nil
+	},
+	20261019120000: func(ctx context.Context, db *sql.DB) (string, error) {
+		n, err := count(ctx, db, "SELECT count(*) FROM filter_events")
+		if err != nil || n == 0 {
+			return "", err
+		}
+		return fmt.Sprintf("будут удалены %d событий outbox и история доставки вебхуков", n), nil
+	},
+}
+
+// LastMigration — значение target для VerifyDown, означающее откат одной
+// последней применённой миграции, как goose down.
+const LastMigration int64 = -1
+
+// VerifyDown проверяет, какие данные потеряются при откате до версии target
+// (сама target остаётся применённой) или, если target = LastMigration, при
+// откате одной миграции.
+func VerifyDown(ctx context.Context, db *sql.DB, p *goose.Provider, target int64) ([]Issue, error) {
+	statuses, err := p.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	paths := make(map[int64]string, len(statuses))
+	for _, s := range statuses {
+		paths[s.Source.Version] = s.Source.Path
+	}
+	applied, err := appliedVersions(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []Issue
+	for _, v := range downVersions(applied, target) {
+		check, ok := downChecks[v]
+		if !ok {
+			continue
+		}
+		reason, err := check(ctx, db)
+		if err != nil {
+			return nil, fmt.Errorf("verify %d: %w", v, err)
+		}
+		if reason != "" {
+			issues = append(issues, Issue{Version: v, Path: paths[v], Reason: reason})
+		}
+	}
+	return issues, nil
+}
+
+// downVersions повторяет выбор миграций в goose: applied идут от последней
+// применённой к первой, откат идёт по ним до первой версии не новее target.
+// С миграциями вне очереди это не то же, что все версии больше target.
+func downVersions(applied []int64, target int64) []int64 {
+	var res []int64
+	for _, v := range applied {
+		if v == 0 || (target != LastMigration && v <= target) {
+			break
+		}
+		res = append(res, v)
+		if target == LastMigration {
+			break
+		}
+	}
+	return res
+}
+
+// appliedVersions читает версии из таблицы goose в порядке применения, от
+// последней к первой, тем же запросом, что и goose при откате.
+func appliedVersions(ctx context.Context, db *sql.DB) ([]int64, error) {
+	store, err := database.NewStore(database.DialectPostgres, goose.DefaultTablename)
+	if err != nil {
+		return nil, err
+	}
+	list, err := store.ListMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]int64, 0, len(list))
+	for _, m := range list {
+		if m.IsApplied {
+			res = append(res, m.Version)
+		}
+	}
+	return res, nil
+}
+
+func exists(ctx context.Context, db *sql.DB, query string) (bool, error) {
+	var ok bool
+	if err := db.QueryRowContext(ctx, query).Scan(&ok); err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+func count(ctx context.Context, db *sql.DB, query string) (int64, error) {
+	var n int64
+	if err := db.QueryRowContext(ctx, query).Scan(&n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
@@ -0,0 +1,33 @@
+package migrate
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDownVersions(t *testing.T) {
+	// 20250901165000 применена вне очереди, после 20250901170100.
+	applied := []int64{20250901165000, 20250901170100, 20250901170000, 20250827222034, 0}
+
+	tests := []struct {
+		name   string
+		target int64
+		want   []int64
+	}{
+		{"last applied", LastMigration, []int64{20250901165000}},
+		{"down to older version", 20250827222034, []int64{20250901165000, 20250901170100, 20250901170000}},
+		// goose останавливается на первой применённой версии не новее target.
+		{"stops at out of order version", 20250901166000, nil},
+		{"reset", 0, []int64{20250901165000, 20250901170100, 20250901170000, 20250827222034}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := downVersions(applied, tt.target); !slices.Equal(got, tt.want) {
+				t.Errorf("downVersions(%d) = %v, want %v", tt.target, got, tt.want)
+			}
+		})
+	}
+	if got := downVersions([]int64{0}, LastMigration); got != nil {
+		t.Errorf("downVersions on empty schema = %v, want none", got)
+	}
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EventFilterCreated = "filter.created"
+	EventFilterUpdated = "filter.updated"
+	EventFilterDeleted = "filter.deleted"
+)
+
+// Event — событие жизненного цикла фильтра из outbox. Filter — состояние после
+// изменения; для filter.deleted пусто.
+type Event struct {
+	ID        int64     `json:"id"`
+	Type      string    `json:"type"`
+	FilterID  uuid.UUID `json:"filter_id"`
+	Filter    *Filter   `json:"filter,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
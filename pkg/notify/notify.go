@@ -0,0 +1,82 @@
+// Package notify подписывается на уведомления Postgres об изменении фильтров
+// (NOTIFY filters_changed из триггера миграции 20261019110000).
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const ChannelFiltersChanged = "filters_changed"
+
+// Change — полезная нагрузка уведомления; Op — insert, update или delete.
+type Change struct {
+	Op string    `json:"op"`
+	ID uuid.UUID `json:"id"`
+}
+
+// Handler получает изменения. Change == nil означает, что соединение
+// восстановлено и уведомления могли быть потеряны: нужно сбросить всё.
+type Handler func(ctx context.Context, ch *Change)
+
+const (
+	minReconnect = time.Second
+	maxReconnect = time.Minute
+	// pingInterval — как часто проверять соединение: без трафика pq не заметит
+	// обрыв и не переподключится.
+	pingInterval = 90 * time.Second
+)
+
+// Listen слушает канал до отмены ctx, переподключаясь при обрывах.
+func Listen(ctx context.Context, dsn, channel string, h Handler) error {
+	l := pq.NewListener(dsn, minReconnect, maxReconnect, func(ev pq.ListenerEventType, err error) {
+		switch ev {
+		case pq.ListenerEventDisconnected:
+			slog.Warn("notify: disconnected", "channel", channel, "error", err)
+		case pq.ListenerEventReconnected:
+			slog.Info("notify: reconnected", "channel", channel)
+		case pq.ListenerEventConnectionAttemptFailed:
+			slog.Warn("notify: connection attempt failed", "channel", channel, "error", err)
+		}
+	})
+	defer l.Close()
+
+	if err := l.Listen(channel); err != nil {
+		return fmt.Errorf("listen %s: %w", channel, err)
+	}
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case n := <-l.Notify:
+			// pq присылает nil после переподключения.
+			if n == nil {
+				h(ctx, nil)
+				continue
+			}
+			var ch Change
+			if err := json.Unmarshal([]byte(n.Extra), &ch); err != nil {
+				slog.Warn("notify: bad payload", "channel", channel, "payload", n.Extra, "error", err)
+				h(ctx, nil)
+				continue
+			}
+			h(ctx, &ch)
+		case <-ticker.C:
+			go func() {
+				if err := l.Ping(); err != nil {
+					slog.Warn("notify: ping failed", "channel", channel, "error", err)
+				}
+			}()
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"search-filter/pkg/migrate"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+// TestListen запускается против локального Postgres из SEARCHFILTER_TEST_POSTGRES_DSN.
+func TestListen(t *testing.T) {
+	dsn := os.Getenv("SEARCHFILTER_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("SEARCHFILTER_TEST_POSTGRES_DSN is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := migrate.Up(ctx, db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	changes := make(chan *Change, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- Listen(ctx, dsn, ChannelFiltersChanged, func(_ context.Context, ch *Change) { changes <- ch })
+	}()
+
+	id := uuid.New()
+	// LISTEN выполняется асинхронно: повторяем изменение, пока не придёт уведомление.
+	if _, err := db.ExecContext(ctx, "INSERT INTO filters (id, name, query) VALUES ($1, 'notify', '{}')", id); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	t.Cleanup(func() { db.Exec("DELETE FROM filters WHERE id = $1", id) })
+
+	tick := time.NewTicker(100 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case ch := <-changes:
+			if ch != nil && ch.ID == id && ch.Op == "update" {
+				cancel()
+				if err := <-done; err != nil {
+					t.Errorf("Listen: %v", err)
+				}
+				return
+			}
+		case <-tick.C:
+			if _, err := db.ExecContext(ctx, "UPDATE filters SET name = 'notify' WHERE id = $1", id); err != nil {
+				t.Fatalf("update: %v", err)
+			}
+		case <-ctx.Done():
+			t.Fatal("no filters_changed notification received")
+		}
+	}
+}
@@ -3,6 +3,7 @@ package placeholder
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"regexp"
 	"search-filter/pkg/types"
@@ -10,6 +11,14 @@ import (
 	"time"
 )
 
+// Причины ошибок рендеринга; проверяются через errors.Is.
+var (
+	ErrParse   = errors.New("template parse")
+	ErrExecute = errors.New("template execute")
+	ErrMarshal = errors.New("marshal query")
+	ErrResult  = errors.New("unmarshal rendered query")
+)
+
 var (
 	reTodayMinus = regexp.MustCompile(`\{\{\s*today-(\d+)d\s*\}\}`)
 	reCurrUser   = regexp.MustCompile(`\{\{\s*current_user\s*\}\}`)
@@ -33,12 +42,12 @@ func RenderTemplate(input string, now time.Time, loc *time.Location, currentUser
 		Option("missingkey=error").
 		Parse(normalizePlaceholders(input))
 	if err != nil {
-		return nil, fmt.Errorf("template parse: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrParse, err)
 	}
 
 	var buf bytes.Buffer
 	if err := tpl.Execute(&buf, nil); err != nil {
-		return nil, fmt.Errorf("template execute: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrExecute, err)
 	}
 
 	return buf.Bytes(), nil
@@ -47,7 +56,7 @@ func RenderTemplate(input string, now time.Time, loc *time.Location, currentUser
 func RenderQuery(q types.Query, now time.Time, loc *time.Location, currentUser int64) (types.Query, error) {
 	raw, err := json.Marshal(q)
 	if err != nil {
-		return nil, fmt.Errorf("marshal query: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrMarshal, err)
 	}
 
 	out, err := RenderTemplate(string(raw), now, loc, currentUser)
@@ -57,7 +66,7 @@ func RenderQuery(q types.Query, now time.Time, loc *time.Location, currentUser i
 
 	var res types.Query
 	if err := json.Unmarshal(out, &res); err != nil {
-		return nil, fmt.Errorf("unmarshal rendered query: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrResult, err)
 	}
 	return res, nil
 }
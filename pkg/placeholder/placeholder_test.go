@@ -0,0 +1,107 @@
+package placeholder
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"search-filter/pkg/types"
+)
+
+func TestRenderQuery(t *testing.T) {
+	loc := time.FixedZone("MSK", 3*60*60)
+	now := time.Date(2025, time.March, 1, 10, 0, 0, 0, loc)
+
+	tests := []struct {
+		name    string
+		in      types.Query
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "no placeholders",
+			in:   types.Query{"tags": []any{"golang"}, "limit": 10},
+			want: `{"limit":10,"tags":["golang"]}`,
+		},
+		{
+			name: "empty",
+			in:   types.Query{},
+			want: `{}`,
+		},
+		{
+			name: "today",
+			in:   types.Query{"date_to": "{{today}}"},
+			want: `{"date_to":"2025-03-01"}`,
+		},
+		{
+			name: "days ago across month boundary",
+			in:   types.Query{"date_from": "{{today-1d}}"},
+			want: `{"date_from":"2025-02-28"}`,
+		},
+		{
+			name: "days ago with spaces",
+			in:   types.Query{"date_from": "{{ today-30d }}"},
+			want: `{"date_from":"2025-01-30"}`,
+		},
+		{
+			name: "current user",
+			in:   types.Query{"author": "{{current_user}}"},
+			want: `{"author":"42"}`,
+		},
+		{
+			name: "nested",
+			in: types.Query{
+				"range": map[string]any{"date": map[string]any{"gte": "{{today-7d}}", "lte": "{{today}}"}},
+				"users": []any{"{{ current_user }}", "7"},
+			},
+			want: `{"range":{"date":{"gte":"2025-02-22","lte":"2025-03-01"}},"users":["42","7"]}`,
+		},
+		{
+			name: "placeholder inside text",
+			in:   types.Query{"text": "since {{today-2d}} by {{current_user}}"},
+			want: `{"text":"since 2025-02-27 by 42"}`,
+		},
+		{
+			name:    "unknown placeholder",
+			in:      types.Query{"x": "{{yesterday}}"},
+			wantErr: true,
+		},
+		{
+			name:    "unclosed action",
+			in:      types.Query{"x": "{{today"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderQuery(tt.in, now, loc, 42)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("RenderQuery() = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenderQuery() error = %v", err)
+			}
+			b, err := json.Marshal(got)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			if string(b) != tt.want {
+				t.Errorf("RenderQuery() = %s, want %s", b, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderQueryDoesNotModifyInput(t *testing.T) {
+	in := types.Query{"date_from": "{{today-7d}}"}
+	if _, err := RenderQuery(in, time.Now(), time.UTC, 1); err != nil {
+		t.Fatalf("RenderQuery() error = %v", err)
+	}
+	if in["date_from"] != "{{today-7d}}" {
+		t.Errorf("input modified: %v", in)
+	}
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"errors"
+
+	"search-filter/pkg/models"
+	"search-filter/pkg/types"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnknownBatchOp = errors.New("unknown batch operation")
+	ErrRolledBack     = errors.New("rolled back: another operation in the atomic batch failed")
+	ErrSkipped        = errors.New("skipped: another operation in the atomic batch failed")
+
+	errBatchAborted = errors.New("batch aborted")
+	errBatchRetry   = errors.New("batch has dangling refs")
+)
+
+type BatchOpKind string
+
+const (
+	BatchCreate BatchOpKind = "create"
+	BatchUpdate BatchOpKind = "update"
+	BatchDelete BatchOpKind = "delete"
+)
+
+// BatchOp — одна операция пакета. Для create непустой ID задаёт идентификатор
+// нового фильтра, для update непустое Name переименовывает фильтр,
+// Force для delete имеет тот же смысл, что и в Repository.Delete.
+type BatchOp struct {
+	Kind  BatchOpKind
+	ID    uuid.UUID
+	Name  string
+	Query types.Query
+	Force bool
+}
+
+// BatchResult — итог операции пакета; Filter пуст для delete и при ошибке.
+type BatchResult struct {
+	Filter *models.Filter
+	Err    error
+}
+
+// abortBatch помечает результаты атомарного пакета, прерванного на операции failed.
+func abortBatch(res []BatchResult, failed int) {
+	for i := range res {
+		switch {
+		case i < failed:
+			res[i] = BatchResult{Err: ErrRolledBack}
+		case i > failed:
+			res[i] = BatchResult{Err: ErrSkipped}
+		}
+	}
+}
+
+// laterCreates — ID фильтров, которые создадут операции пакета после i, кроме
+// операций из failed. Ссылка на такой фильтр ещё не повисла: цель появится
+// позже в том же пакете.
+func laterCreates(ops []BatchOp, i int, failed map[int]bool) map[uuid.UUID]bool {
+	res := map[uuid.UUID]bool{}
+	for j := i + 1; j < len(ops); j++ {
+		if ops[j].Kind == BatchCreate && ops[j].ID != uuid.Nil && !failed[j] {
+			res[ops[j].ID] = true
+		}
+	}
+	return res
+}
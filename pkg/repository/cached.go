@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+
+	"search-filter/pkg/cache"
+	"search-filter/pkg/models"
+	"search-filter/pkg/types"
+
+	"github.com/google/uuid"
+)
+
+// CachedRepository — read-through кэш Get поверх другого Repository.
+// Изменения и удаления инвалидируют весь кэш: отрендеренные запросы фильтров,
+// ссылающихся на изменённый, тоже устаревают.
+type CachedRepository struct {
+	Repository
+	cache *cache.Cache
+}
+
+func NewCachedRepository(repo Repository, c *cache.Cache) *CachedRepository {
+	return &CachedRepository{Repository: repo, cache: c}
+}
+
+func FilterCacheKey(id uuid.UUID) string {
+	return "filter:" + id.String()
+}
+
+func (r *CachedRepository) Get(ctx context.Context, id uuid.UUID) (*models.Filter, error) {
+	var f models.Filter
+	gen, ok := r.cache.Get(ctx, FilterCacheKey(id), &f)
+	if ok {
+		return &f, nil
+	}
+	got, err := r.Repository.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.Set(ctx, gen, FilterCacheKey(id), got)
+	return got, nil
+}
+
+func (r *CachedRepository) Update(ctx context.Context, id uuid.UUID, query types.Query) (*models.Filter, error) {
+	f, err := r.Repository.Update(ctx, id, query)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.Invalidate(ctx)
+	return f, nil
+}
+
+func (r *CachedRepository) Delete(ctx context.Context, id uuid.UUID, force bool) error {
+	if err := r.Repository.Delete(ctx, id, force); err != nil {
+		return err
+	}
+	r.cache.Invalidate(ctx)
+	return nil
+}
+
+func (r *CachedRepository) Batch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error) {
+	res, err := r.Repository.Batch(ctx, ops, atomic)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.Invalidate(ctx)
+	return res, nil
+}
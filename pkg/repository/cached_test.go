@@ -0,0 +1,115 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"search-filter/pkg/cache"
+	"search-filter/pkg/models"
+	"search-filter/pkg/repository"
+	"search-filter/pkg/repository/repotest"
+	"search-filter/pkg/types"
+
+	"github.com/google/uuid"
+)
+
+func TestCachedRepository(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.Repository {
+		c := cache.New(cache.NewLRU(100), "test:", time.Minute)
+		return repository.NewCachedRepository(repository.NewMemoryRepository(), c)
+	})
+}
+
+func TestCachedRepositoryReadThrough(t *testing.T) {
+	ctx := context.Background()
+	inner := repository.NewMemoryRepository()
+	c := cache.New(cache.NewLRU(100), "test:", time.Minute)
+	r := repository.NewCachedRepository(inner, c)
+
+	f, err := r.Create(ctx, "a", types.Query{"v": "1"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := r.Get(ctx, f.ID); err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+	}
+	if st := c.Stats().Kinds["filter"]; st.Hits != 2 || st.Misses != 1 {
+		t.Errorf("stats = %+v, want 2 hits and 1 miss", st)
+	}
+
+	// Изменение в обход декоратора не видно до инвалидации.
+	if _, err := inner.Update(ctx, f.ID, types.Query{"v": "2"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, _ := r.Get(ctx, f.ID)
+	if got.Query["v"] != "1" {
+		t.Fatalf("Get = %v, want cached value", got.Query)
+	}
+
+	if _, err := r.Update(ctx, f.ID, types.Query{"v": "3"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, _ = r.Get(ctx, f.ID)
+	if got.Query["v"] != "3" {
+		t.Errorf("Get after Update = %v, want fresh value", got.Query)
+	}
+
+	if err := r.Delete(ctx, f.ID, false); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := r.Get(ctx, f.ID); err != repository.ErrNotFound {
+		t.Errorf("Get after Delete err = %v, want ErrNotFound", err)
+	}
+}
+
+// racingRepository вызывает onGet после чтения из хранилища, но до возврата
+// значения — то есть между промахом кэша и записью в него.
+type racingRepository struct {
+	repository.Repository
+	onGet func()
+}
+
+func (r *racingRepository) Get(ctx context.Context, id uuid.UUID) (*models.Filter, error) {
+	f, err := r.Repository.Get(ctx, id)
+	if r.onGet != nil {
+		onGet := r.onGet
+		r.onGet = nil
+		onGet()
+	}
+	return f, err
+}
+
+func TestCachedRepositoryInvalidateDuringFill(t *testing.T) {
+	ctx := context.Background()
+	inner := &racingRepository{Repository: repository.NewMemoryRepository()}
+	c := cache.New(cache.NewLRU(100), "test:", time.Minute)
+	r := repository.NewCachedRepository(inner, c)
+
+	f, err := r.Create(ctx, "a", types.Query{"v": "1"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	inner.onGet = func() {
+		if _, err := r.Update(ctx, f.ID, types.Query{"v": "2"}); err != nil {
+			t.Errorf("Update: %v", err)
+		}
+	}
+	got, err := r.Get(ctx, f.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Query["v"] != "1" {
+		t.Fatalf("first Get = %v, want the value read before Update", got.Query)
+	}
+
+	got, err = r.Get(ctx, f.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Query["v"] != "2" {
+		t.Errorf("Get after Update = %v: stale value was cached", got.Query)
+	}
+}
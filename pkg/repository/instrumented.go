@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"search-filter/pkg/models"
+	"search-filter/pkg/types"
+
+	"github.com/google/uuid"
+)
+
+// Observer получает имя метода репозитория, длительность вызова и его ошибку.
+type Observer func(method string, d time.Duration, err error)
+
+// InstrumentedRepository замеряет каждый вызов другого Repository, например для метрик.
+type InstrumentedRepository struct {
+	repo    Repository
+	observe Observer
+}
+
+func NewInstrumentedRepository(repo Repository, observe Observer) *InstrumentedRepository {
+	return &InstrumentedRepository{repo: repo, observe: observe}
+}
+
+func (r *InstrumentedRepository) Create(ctx context.Context, name string, query types.Query) (*models.Filter, error) {
+	start := time.Now()
+	f, err := r.repo.Create(ctx, name, query)
+	r.observe("Create", time.Since(start), err)
+	return f, err
+}
+
+func (r *InstrumentedRepository) List(ctx context.Context) ([]models.FilterListItem, error) {
+	start := time.Now()
+	items, err := r.repo.List(ctx)
+	r.observe("List", time.Since(start), err)
+	return items, err
+}
+
+func (r *InstrumentedRepository) Get(ctx context.Context, id uuid.UUID) (*models.Filter, error) {
+	start := time.Now()
+	f, err := r.repo.Get(ctx, id)
+	r.observe("Get", time.Since(start), err)
+	return f, err
+}
+
+func (r *InstrumentedRepository) Update(ctx context.Context, id uuid.UUID, query types.Query) (*models.Filter, error) {
+	start := time.Now()
+	f, err := r.repo.Update(ctx, id, query)
+	r.observe("Update", time.Since(start), err)
+	return f, err
+}
+
+func (r *InstrumentedRepository) Delete(ctx context.Context, id uuid.UUID, force bool) error {
+	start := time.Now()
+	err := r.repo.Delete(ctx, id, force)
+	r.observe("Delete", time.Since(start), err)
+	return err
+}
+
+func (r *InstrumentedRepository) Dependents(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	start := time.Now()
+	ids, err := r.repo.Dependents(ctx, id)
+	r.observe("Dependents", time.Since(start), err)
+	return ids, err
+}
+
+func (r *InstrumentedRepository) Batch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error) {
+	start := time.Now()
+	res, err := r.repo.Batch(ctx, ops, atomic)
+	r.observe("Batch", time.Since(start), err)
+	return res, err
+}
@@ -0,0 +1,302 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"search-filter/pkg/compose"
+	"search-filter/pkg/models"
+	"search-filter/pkg/types"
+
+	"github.com/google/uuid"
+)
+
+// MemoryRepository хранит фильтры в памяти процесса; для тестов и локальной разработки.
+// Запросы копируются через JSON на входе и выходе, как при хранении в JSONB.
+type MemoryRepository struct {
+	mu      sync.RWMutex
+	filters map[uuid.UUID]models.Filter
+	// deps: filter_id → depends_on, как таблица filter_dependencies.
+	deps map[uuid.UUID][]uuid.UUID
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		filters: map[uuid.UUID]models.Filter{},
+		deps:    map[uuid.UUID][]uuid.UUID{},
+	}
+}
+
+func (r *MemoryRepository) Create(ctx context.Context, name string, query types.Query) (*models.Filter, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.create(uuid.Nil, name, query, nil)
+}
+
+func (r *MemoryRepository) List(ctx context.Context) ([]models.FilterListItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res := make([]models.FilterListItem, 0, len(r.filters))
+	for _, f := range r.filters {
+		c, err := clone(f)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, c.ToListItem())
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID.String() < res[j].ID.String() })
+	return res, nil
+}
+
+func (r *MemoryRepository) Get(ctx context.Context, id uuid.UUID) (*models.Filter, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	f, ok := r.filters[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return clone(f)
+}
+
+func (r *MemoryRepository) Update(ctx context.Context, id uuid.UUID, query types.Query) (*models.Filter, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.update(id, "", query, nil)
+}
+
+func (r *MemoryRepository) Delete(ctx context.Context, id uuid.UUID, force bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.remove(id, force)
+}
+
+func (r *MemoryRepository) Dependents(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.dependents(id), nil
+}
+
+func (r *MemoryRepository) Batch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	failed := map[int]bool{}
+	for {
+		res, dangling := r.batch(ops, atomic, failed)
+		if len(dangling) == 0 {
+			return res, nil
+		}
+		for _, i := range dangling {
+			failed[i] = true
+		}
+	}
+}
+
+// batch повторяет reformRepository.batch: ссылка проверяется сразу после
+// операции, цели, которые создаст операция дальше по пакету, считаются
+// существующими. Если такая цель не создалась, изменения откатываются, и в
+// dangling возвращаются номера операций с повисшими ссылками.
+func (r *MemoryRepository) batch(ops []BatchOp, atomic bool, failed map[int]bool) ([]BatchResult, []int) {
+	filters, deps := r.snapshot()
+	rollback := func() { r.filters, r.deps = filters, deps }
+
+	res := make([]BatchResult, len(ops))
+	for i, op := range ops {
+		if failed[i] {
+			res[i] = BatchResult{Err: ErrUnknownRef}
+			if atomic {
+				rollback()
+				abortBatch(res, i)
+				return res, nil
+			}
+			continue
+		}
+
+		// Операции меняют состояние только после всех проверок, поэтому
+		// неудавшуюся откатывать не нужно.
+		var (
+			f   *models.Filter
+			err error
+		)
+		switch op.Kind {
+		case BatchCreate:
+			f, err = r.create(op.ID, op.Name, op.Query, laterCreates(ops, i, failed))
+		case BatchUpdate:
+			f, err = r.update(op.ID, op.Name, op.Query, laterCreates(ops, i, failed))
+		case BatchDelete:
+			err = r.remove(op.ID, op.Force)
+		default:
+			err = ErrUnknownBatchOp
+		}
+		res[i] = BatchResult{Filter: f, Err: err}
+		if err != nil && atomic {
+			rollback()
+			abortBatch(res, i)
+			return res, nil
+		}
+	}
+
+	var dangling []int
+	for i, it := range res {
+		if it.Err == nil && it.Filter != nil && r.danglingRefs(it.Filter.ID) {
+			dangling = append(dangling, i)
+		}
+	}
+	if len(dangling) > 0 {
+		rollback()
+	}
+	return res, dangling
+}
+
+// create добавляет фильтр. Ссылки на отсутствующие фильтры, кроме pending,
+// отклоняются с ErrUnknownRef.
+func (r *MemoryRepository) create(id uuid.UUID, name string, query types.Query, pending map[uuid.UUID]bool) (*models.Filter, error) {
+	if id == uuid.Nil {
+		id = uuid.New()
+	}
+	if _, exists := r.filters[id]; exists {
+		return nil, ErrAlreadyExists
+	}
+	refs, err := r.refs(id, query, pending)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	f, err := clone(models.Filter{ID: id, Name: name, Query: query, CreatedAt: now, UpdatedAt: now})
+	if err != nil {
+		return nil, err
+	}
+	r.filters[id] = *f
+	r.setDependencies(id, refs)
+	return clone(*f)
+}
+
+func (r *MemoryRepository) update(id uuid.UUID, name string, query types.Query, pending map[uuid.UUID]bool) (*models.Filter, error) {
+	f, ok := r.filters[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	refs, err := r.refs(id, query, pending)
+	if err != nil {
+		return nil, err
+	}
+
+	if name != "" {
+		f.Name = name
+	}
+	f.Query = query
+	c, err := clone(f)
+	if err != nil {
+		return nil, err
+	}
+	r.filters[id] = *c
+	r.setDependencies(id, refs)
+	return clone(*c)
+}
+
+func (r *MemoryRepository) remove(id uuid.UUID, force bool) error {
+	if _, ok := r.filters[id]; !ok {
+		return ErrNotFound
+	}
+	if deps := r.dependents(id); len(deps) > 0 {
+		if !force {
+			return &DependentsError{Dependents: deps}
+		}
+		for _, d := range deps {
+			r.deps[d] = without(r.deps[d], id)
+		}
+	}
+	delete(r.filters, id)
+	delete(r.deps, id)
+	return nil
+}
+
+// refs возвращает ссылки запроса фильтра id. Ссылка на отсутствующий фильтр,
+// кроме pending, — ErrUnknownRef, как внешний ключ filter_dependencies.depends_on.
+func (r *MemoryRepository) refs(id uuid.UUID, query types.Query, pending map[uuid.UUID]bool) ([]uuid.UUID, error) {
+	refs, err := compose.Refs(query)
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range refs {
+		if _, ok := r.filters[ref]; !ok && ref != id && !pending[ref] {
+			return nil, ErrUnknownRef
+		}
+	}
+	return refs, nil
+}
+
+// danglingRefs сообщает, ссылается ли фильтр id на отсутствующие фильтры.
+func (r *MemoryRepository) danglingRefs(id uuid.UUID) bool {
+	for _, ref := range r.deps[id] {
+		if _, ok := r.filters[ref]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MemoryRepository) dependents(id uuid.UUID) []uuid.UUID {
+	var res []uuid.UUID
+	for from, to := range r.deps {
+		for _, d := range to {
+			if d == id {
+				res = append(res, from)
+				break
+			}
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].String() < res[j].String() })
+	return res
+}
+
+func (r *MemoryRepository) setDependencies(id uuid.UUID, refs []uuid.UUID) {
+	if len(refs) == 0 {
+		delete(r.deps, id)
+		return
+	}
+	r.deps[id] = refs
+}
+
+func (r *MemoryRepository) snapshot() (map[uuid.UUID]models.Filter, map[uuid.UUID][]uuid.UUID) {
+	filters := make(map[uuid.UUID]models.Filter, len(r.filters))
+	for id, f := range r.filters {
+		filters[id] = f
+	}
+	deps := make(map[uuid.UUID][]uuid.UUID, len(r.deps))
+	for id, d := range r.deps {
+		deps[id] = d
+	}
+	return filters, deps
+}
+
+func without(ids []uuid.UUID, id uuid.UUID) []uuid.UUID {
+	res := make([]uuid.UUID, 0, len(ids))
+	for _, v := range ids {
+		if v != id {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// clone копирует фильтр, пропуская запрос через JSON, чтобы вызывающий код
+// не разделял с хранилищем вложенные map и slice.
+func clone(f models.Filter) (*models.Filter, error) {
+	raw, err := json.Marshal(f.Query)
+	if err != nil {
+		return nil, err
+	}
+	var q types.Query
+	if err := json.Unmarshal(raw, &q); err != nil {
+		return nil, err
+	}
+	f.Query = q
+	return &f, nil
+}
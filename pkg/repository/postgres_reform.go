@@ -2,82 +2,69 @@ package repository
 
 import (
 	"context"
-	"errors"
-	"time"
-
-	"github.com/google/uuid"
-	reform "gopkg.in/reform.v1"
+	"encoding/json"
 
 	"search-filter/pkg/models"
-	"search-filter/pkg/types"
+
+	reform "gopkg.in/reform.v1"
 )
 
-type PostgresRepository struct{ db *reform.DB }
+// PostgresRepository пишет события изменений в outbox filter_events в той же
+// транзакции, что и само изменение.
+type PostgresRepository struct{ reformRepository }
 
-func NewPostgresRepository(db *reform.DB) *PostgresRepository { return &PostgresRepository{db: db} }
+func NewPostgresRepository(db *reform.DB) *PostgresRepository {
+	return &PostgresRepository{reformRepository{db: db, outbox: true}}
+}
 
-func (r *PostgresRepository) Create(ctx context.Context, name string, query types.Query) (*models.Filter, error) {
-	now := time.Now().UTC()
-	f := &models.Filter{
-		Name:      name,
-		Query:     query,
-		CreatedAt: now,
-		UpdatedAt: now,
-	}
-	if err := r.db.WithContext(ctx).Insert(f); err != nil {
-		return nil, err
-	}
-	return f, nil
+// EventLog отдаёт события outbox по возрастанию id.
+type EventLog interface {
+	// Events возвращает до limit событий с id больше since.
+	Events(ctx context.Context, since int64, limit int) ([]models.Event, error)
+	// LastEventID возвращает id последнего события, доступного Events, или 0.
+	LastEventID(ctx context.Context) (int64, error)
 }
 
-func (r *PostgresRepository) List(ctx context.Context) ([]models.FilterListItem, error) {
-	rows, err := r.db.WithContext(ctx).SelectAllFrom(models.FilterTable, "ORDER BY id ASC")
+// Events отдаёт только события, старше которых не осталось незавершённых
+// транзакций: BIGSERIAL выдаёт id до коммита, и без этого условия читатель мог бы
+// продвинуть курсор мимо события, закоммиченного позже большего id.
+func (r *PostgresRepository) Events(ctx context.Context, since int64, limit int) ([]models.Event, error) {
+	rows, err := r.querier(ctx).Query(`
+		SELECT id, type, filter_id, payload, created_at
+		FROM filter_events
+		WHERE id > $1 AND tx_id < pg_snapshot_xmin(pg_current_snapshot())
+		ORDER BY id
+		LIMIT $2`, since, limit)
 	if err != nil {
 		return nil, err
 	}
-	res := make([]models.FilterListItem, 0, len(rows))
-	for _, s := range rows {
-		f := s.(*models.Filter)
-		res = append(res, f.ToListItem())
-	}
-	return res, nil
-}
+	defer rows.Close()
 
-func (r *PostgresRepository) Get(ctx context.Context, id uuid.UUID) (*models.Filter, error) {
-	var f models.Filter
-	if err := r.db.WithContext(ctx).FindByPrimaryKeyTo(&f, id); err != nil {
-		if errors.Is(err, reform.ErrNoRows) {
-			return nil, ErrNotFound
+	res := []models.Event{}
+	for rows.Next() {
+		var (
+			ev      models.Event
+			payload []byte
+		)
+		if err := rows.Scan(&ev.ID, &ev.Type, &ev.FilterID, &payload, &ev.CreatedAt); err != nil {
+			return nil, err
 		}
-		return nil, err
-	}
-	return &f, nil
-}
-
-func (r *PostgresRepository) Update(ctx context.Context, id uuid.UUID, query types.Query) (*models.Filter, error) {
-	var f models.Filter
-	if err := r.db.WithContext(ctx).FindByPrimaryKeyTo(&f, id); err != nil {
-		if errors.Is(err, reform.ErrNoRows) {
-			return nil, ErrNotFound
+		if payload != nil {
+			ev.Filter = &models.Filter{}
+			if err := json.Unmarshal(payload, ev.Filter); err != nil {
+				return nil, err
+			}
 		}
-		return nil, err
+		res = append(res, ev)
 	}
-
-	f.Query = query
-
-	if err := r.db.WithContext(ctx).Update(&f); err != nil {
-		return nil, err
-	}
-	return &f, nil
+	return res, rows.Err()
 }
 
-func (r *PostgresRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	n, err := r.db.WithContext(ctx).DeleteFrom(models.FilterTable, "WHERE id = $1", id)
-	if err != nil {
-		return err
-	}
-	if n == 0 {
-		return ErrNotFound
-	}
-	return nil
+func (r *PostgresRepository) LastEventID(ctx context.Context) (int64, error) {
+	var id int64
+	err := r.querier(ctx).QueryRow(`
+		SELECT COALESCE(max(id), 0)
+		FROM filter_events
+		WHERE tx_id < pg_snapshot_xmin(pg_current_snapshot())`).Scan(&id)
+	return id, err
 }
@@ -0,0 +1,45 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"search-filter/pkg/migrate"
+	"search-filter/pkg/repository"
+	"search-filter/pkg/repository/repotest"
+
+	_ "github.com/lib/pq"
+	reform "gopkg.in/reform.v1"
+	"gopkg.in/reform.v1/dialects/postgresql"
+)
+
+// TestPostgresRepository запускается против локального Postgres, DSN задаётся в
+// SEARCHFILTER_TEST_POSTGRES_DSN. Миграции применяются, таблицы очищаются перед
+// каждым подтестом — используйте отдельную базу.
+func TestPostgresRepository(t *testing.T) {
+	dsn := os.Getenv("SEARCHFILTER_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("SEARCHFILTER_TEST_POSTGRES_DSN is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	ctx := context.Background()
+	if _, err := migrate.Up(ctx, db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	rdb := reform.NewDB(db, postgresql.Dialect, nil)
+
+	repotest.Run(t, func(t *testing.T) repository.Repository {
+		if _, err := db.ExecContext(ctx, "TRUNCATE filters CASCADE"); err != nil {
+			t.Fatalf("truncate: %v", err)
+		}
+		return repository.NewPostgresRepository(rdb)
+	})
+}
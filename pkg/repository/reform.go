@@ -0,0 +1,407 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	reform "gopkg.in/reform.v1"
+
+	"search-filter/pkg/compose"
+	"search-filter/pkg/logging"
+	"search-filter/pkg/models"
+	"search-filter/pkg/types"
+)
+
+// reformRepository — общая реализация Repository поверх reform для Postgres и SQLite.
+// SQL-хвосты собираются через Placeholder диалекта.
+type reformRepository struct {
+	db *reform.DB
+	// newID генерирует ID новых фильтров; nil — ID выдаёт БД.
+	newID func() uuid.UUID
+	// outbox включает запись событий в filter_events в транзакции изменения.
+	outbox bool
+}
+
+// querier возвращает Querier с контекстом запроса, помеченный его request ID.
+func (r *reformRepository) querier(ctx context.Context) *reform.Querier {
+	return tagged(ctx, r.db.WithContext(ctx))
+}
+
+// tagged добавляет к запросам комментарий /* request_id:… */: по нему запрос
+// находится и в журнале SQL, и в pg_stat_activity.
+func tagged(ctx context.Context, q *reform.Querier) *reform.Querier {
+	if id := logging.RequestID(ctx); id != "" {
+		return q.WithTag("request_id:%s", id)
+	}
+	return q
+}
+
+func (r *reformRepository) Create(ctx context.Context, name string, query types.Query) (*models.Filter, error) {
+	var f *models.Filter
+	err := r.db.InTransactionContext(ctx, nil, func(tx *reform.TX) error {
+		var err error
+		q := tagged(ctx, tx.Querier)
+		if f, err = r.create(q, uuid.Nil, name, query); err != nil {
+			return err
+		}
+		return checkRefs(q, f.ID, nil)
+	})
+	if err != nil {
+		return nil, refError(err)
+	}
+	return f, nil
+}
+
+func (r *reformRepository) List(ctx context.Context) ([]models.FilterListItem, error) {
+	rows, err := r.querier(ctx).SelectAllFrom(models.FilterTable, "ORDER BY id ASC")
+	if err != nil {
+		return nil, err
+	}
+	res := make([]models.FilterListItem, 0, len(rows))
+	for _, s := range rows {
+		f := s.(*models.Filter)
+		res = append(res, f.ToListItem())
+	}
+	return res, nil
+}
+
+func (r *reformRepository) Get(ctx context.Context, id uuid.UUID) (*models.Filter, error) {
+	var f models.Filter
+	if err := r.querier(ctx).FindByPrimaryKeyTo(&f, id); err != nil {
+		if errors.Is(err, reform.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (r *reformRepository) Update(ctx context.Context, id uuid.UUID, query types.Query) (*models.Filter, error) {
+	var f *models.Filter
+	err := r.db.InTransactionContext(ctx, nil, func(tx *reform.TX) error {
+		var err error
+		q := tagged(ctx, tx.Querier)
+		if f, err = r.update(q, id, "", query); err != nil {
+			return err
+		}
+		return checkRefs(q, f.ID, nil)
+	})
+	if err != nil {
+		return nil, refError(err)
+	}
+	return f, nil
+}
+
+func (r *reformRepository) Delete(ctx context.Context, id uuid.UUID, force bool) error {
+	return r.db.InTransactionContext(ctx, nil, func(tx *reform.TX) error {
+		return r.remove(tagged(ctx, tx.Querier), id, force)
+	})
+}
+
+func (r *reformRepository) Dependents(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	return dependents(r.querier(ctx), id)
+}
+
+func (r *reformRepository) Batch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error) {
+	failed := map[int]bool{}
+	for {
+		res, dangling, err := r.batch(ctx, ops, atomic, failed)
+		if err != nil {
+			return nil, refError(err)
+		}
+		if len(dangling) == 0 {
+			return res, nil
+		}
+		for _, i := range dangling {
+			failed[i] = true
+		}
+	}
+}
+
+// batch выполняет пакет одной транзакцией. Операции из failed не выполняются и
+// получают ErrUnknownRef. Ссылка проверяется в точке сохранения своей операции;
+// цель, которую создаст операция дальше по пакету, считается существующей. Если
+// та не удалась, batch откатывает транзакцию и возвращает в dangling номера
+// операций с повисшими ссылками — пакет повторяется без них.
+func (r *reformRepository) batch(ctx context.Context, ops []BatchOp, atomic bool, failed map[int]bool) (res []BatchResult, dangling []int, err error) {
+	res = make([]BatchResult, len(ops))
+	err = r.db.InTransactionContext(ctx, nil, func(tx *reform.TX) error {
+		q := tagged(ctx, tx.Querier)
+		for i, op := range ops {
+			if failed[i] {
+				res[i] = BatchResult{Err: ErrUnknownRef}
+				if atomic {
+					abortBatch(res, i)
+					return errBatchAborted
+				}
+				continue
+			}
+			if !atomic {
+				if _, err := tx.Exec("SAVEPOINT batch_item"); err != nil {
+					return err
+				}
+			}
+
+			f, err := r.applyOp(q, op)
+			if err == nil && f != nil {
+				err = checkRefs(q, f.ID, laterCreates(ops, i, failed))
+			}
+			res[i] = BatchResult{Filter: f, Err: err}
+			if err == nil {
+				if !atomic {
+					if _, err := tx.Exec("RELEASE SAVEPOINT batch_item"); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			res[i].Filter = nil
+			if atomic {
+				abortBatch(res, i)
+				return errBatchAborted
+			}
+			if _, err := tx.Exec("ROLLBACK TO SAVEPOINT batch_item"); err != nil {
+				return err
+			}
+		}
+
+		for i, it := range res {
+			if it.Err != nil || it.Filter == nil {
+				continue
+			}
+			if err := checkRefs(q, it.Filter.ID, nil); errors.Is(err, ErrUnknownRef) {
+				dangling = append(dangling, i)
+			} else if err != nil {
+				return err
+			}
+		}
+		if len(dangling) > 0 {
+			return errBatchRetry
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errBatchAborted):
+		return res, nil, nil
+	case errors.Is(err, errBatchRetry):
+		return nil, dangling, nil
+	case err != nil:
+		return nil, nil, err
+	}
+	return res, nil, nil
+}
+
+func (r *reformRepository) applyOp(q *reform.Querier, op BatchOp) (*models.Filter, error) {
+	switch op.Kind {
+	case BatchCreate:
+		return r.create(q, op.ID, op.Name, op.Query)
+	case BatchUpdate:
+		return r.update(q, op.ID, op.Name, op.Query)
+	case BatchDelete:
+		return nil, r.remove(q, op.ID, op.Force)
+	}
+	return nil, ErrUnknownBatchOp
+}
+
+// create вставляет фильтр; при id == uuid.Nil идентификатор выдаёт newID или БД.
+func (r *reformRepository) create(q *reform.Querier, id uuid.UUID, name string, query types.Query) (*models.Filter, error) {
+	if id == uuid.Nil && r.newID != nil {
+		id = r.newID()
+	}
+	now := time.Now().UTC()
+	f := &models.Filter{
+		ID:        id,
+		Name:      name,
+		Query:     query,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := q.Insert(f); err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrAlreadyExists
+		}
+		return nil, err
+	}
+	if err := setDependencies(q, f.ID, query); err != nil {
+		return nil, err
+	}
+	if err := r.emit(q, models.EventFilterCreated, f.ID, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// update заменяет запрос фильтра и, если name не пуст, его имя.
+func (r *reformRepository) update(q *reform.Querier, id uuid.UUID, name string, query types.Query) (*models.Filter, error) {
+	var f models.Filter
+	if err := q.FindByPrimaryKeyTo(&f, id); err != nil {
+		if errors.Is(err, reform.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if name != "" {
+		f.Name = name
+	}
+	f.Query = query
+
+	if err := q.Update(&f); err != nil {
+		return nil, err
+	}
+	if err := setDependencies(q, f.ID, query); err != nil {
+		return nil, err
+	}
+	if err := r.emit(q, models.EventFilterUpdated, f.ID, &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (r *reformRepository) remove(q *reform.Querier, id uuid.UUID, force bool) error {
+	if force {
+		if _, err := q.Exec("DELETE FROM filter_dependencies WHERE depends_on = "+q.Placeholder(1), id); err != nil {
+			return err
+		}
+	} else {
+		deps, err := dependents(q, id)
+		if err != nil {
+			return err
+		}
+		if len(deps) > 0 {
+			return &DependentsError{Dependents: deps}
+		}
+	}
+
+	n, err := q.DeleteFrom(models.FilterTable, "WHERE id = "+q.Placeholder(1), id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return r.emit(q, models.EventFilterDeleted, id, nil)
+}
+
+// emit пишет событие в outbox; f — состояние фильтра после изменения или nil.
+func (r *reformRepository) emit(q *reform.Querier, typ string, id uuid.UUID, f *models.Filter) error {
+	if !r.outbox {
+		return nil
+	}
+	var payload []byte
+	if f != nil {
+		var err error
+		if payload, err = json.Marshal(f); err != nil {
+			return err
+		}
+	}
+	_, err := q.Exec(
+		"INSERT INTO filter_events (type, filter_id, payload) VALUES ("+q.Placeholder(1)+", "+q.Placeholder(2)+", "+q.Placeholder(3)+")",
+		typ, id, payload,
+	)
+	return err
+}
+
+func dependents(q *reform.Querier, id uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.Query("SELECT filter_id FROM filter_dependencies WHERE depends_on = "+q.Placeholder(1)+" ORDER BY filter_id", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []uuid.UUID
+	for rows.Next() {
+		var d uuid.UUID
+		if err := rows.Scan(&d); err != nil {
+			return nil, err
+		}
+		res = append(res, d)
+	}
+	return res, rows.Err()
+}
+
+func setDependencies(q *reform.Querier, id uuid.UUID, query types.Query) error {
+	refs, err := compose.Refs(query)
+	if err != nil {
+		return err
+	}
+	if _, err := q.Exec("DELETE FROM filter_dependencies WHERE filter_id = "+q.Placeholder(1), id); err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		if _, err := q.Exec(
+			"INSERT INTO filter_dependencies (filter_id, depends_on) VALUES ("+q.Placeholder(1)+", "+q.Placeholder(2)+")", id, ref,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkRefs возвращает ErrUnknownRef, если запрос фильтра id ссылается на
+// отсутствующий фильтр, кроме перечисленных в pending. Вызывается перед
+// коммитом: отложенный внешний ключ сработал бы только при коммите, а SQLite
+// после такой ошибки оставляет транзакцию открытой.
+func checkRefs(q *reform.Querier, id uuid.UUID, pending map[uuid.UUID]bool) error {
+	rows, err := q.Query(`
+		SELECT d.depends_on FROM filter_dependencies AS d
+		WHERE d.filter_id = `+q.Placeholder(1)+`
+		  AND NOT EXISTS (SELECT 1 FROM filters AS f WHERE f.id = d.depends_on)`, id)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ref uuid.UUID
+		if err := rows.Scan(&ref); err != nil {
+			return err
+		}
+		if !pending[ref] {
+			return ErrUnknownRef
+		}
+	}
+	return rows.Err()
+}
+
+// refError заменяет нарушение внешнего ключа filter_dependencies.depends_on на
+// ErrUnknownRef: в Postgres фильтр, на который ссылаются, могут удалить между
+// checkRefs и коммитом.
+func refError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return ErrUnknownRef
+	}
+	var coded interface{ Code() int }
+	if errors.As(err, &coded) && coded.Code() == sqliteConstraintForeignKey {
+		return ErrUnknownRef
+	}
+	return err
+}
+
+// isUniqueViolation распознаёт нарушение уникальности в Postgres (lib/pq) и SQLite.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	var coded interface{ Code() int }
+	if errors.As(err, &coded) {
+		switch coded.Code() {
+		case sqliteConstraintPrimaryKey, sqliteConstraintUnique:
+			return true
+		}
+	}
+	return false
+}
+
+// Расширенные коды ошибок SQLite, https://sqlite.org/rescode.html.
+const (
+	sqliteConstraintPrimaryKey = 1555
+	sqliteConstraintUnique     = 2067
+	sqliteConstraintForeignKey = 787
+)
@@ -10,12 +10,41 @@ import (
 	"github.com/google/uuid"
 )
 
-var ErrNotFound = errors.New("filter not found")
+var (
+	ErrNotFound      = errors.New("filter not found")
+	ErrHasDependents = errors.New("filter has dependents")
+	ErrAlreadyExists = errors.New("filter already exists")
+	// ErrUnknownRef — запрос ссылается на фильтр, которого нет в БД.
+	ErrUnknownRef = errors.New("referenced filter does not exist")
+)
+
+// DependentsError — отказ удалить фильтр, на который ссылаются другие.
+// Dependents прочитаны в той же транзакции, что и проверка;
+// errors.Is(err, ErrHasDependents) для неё истинно.
+type DependentsError struct {
+	Dependents []uuid.UUID
+}
+
+func (e *DependentsError) Error() string {
+	return ErrHasDependents.Error()
+}
+
+func (e *DependentsError) Unwrap() error {
+	return ErrHasDependents
+}
 
 type Repository interface {
 	Create(ctx context.Context, name string, query types.Query) (*models.Filter, error)
 	List(ctx context.Context) ([]models.FilterListItem, error)
 	Get(ctx context.Context, id uuid.UUID) (*models.Filter, error)
 	Update(ctx context.Context, id uuid.UUID, query types.Query) (*models.Filter, error)
-	Delete(ctx context.Context, id uuid.UUID) error
+	// Delete удаляет фильтр. Если на него ссылаются другие фильтры, возвращает
+	// *DependentsError (ErrHasDependents), пока не указан force.
+	Delete(ctx context.Context, id uuid.UUID, force bool) error
+	// Dependents возвращает ID фильтров, ссылающихся на id.
+	Dependents(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error)
+	// Batch выполняет операции в одной транзакции. При atomic первая ошибка откатывает
+	// весь пакет, иначе откатывается только неудавшаяся операция. Ошибки отдельных
+	// операций возвращаются в BatchResult, а не вторым значением.
+	Batch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error)
 }
@@ -0,0 +1,532 @@
+// Package repotest — набор проверок, которые должна проходить любая реализация
+// repository.Repository. Подключается из _test.go каждой реализации через Run.
+package repotest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+
+	"search-filter/pkg/compose"
+	"search-filter/pkg/models"
+	"search-filter/pkg/repository"
+	"search-filter/pkg/types"
+
+	"github.com/google/uuid"
+)
+
+// Factory возвращает пустой репозиторий; вызывается для каждого подтеста.
+type Factory func(t *testing.T) repository.Repository
+
+// Run прогоняет весь набор против репозиториев из newRepo.
+func Run(t *testing.T, newRepo Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, r repository.Repository)
+	}{
+		{"CreateGet", testCreateGet},
+		{"GetNotFound", testGetNotFound},
+		{"ListEmpty", testListEmpty},
+		{"ListOrder", testListOrder},
+		{"Update", testUpdate},
+		{"UpdateNotFound", testUpdateNotFound},
+		{"Delete", testDelete},
+		{"DeleteNotFound", testDeleteNotFound},
+		{"Dependents", testDependents},
+		{"DeleteWithDependents", testDeleteWithDependents},
+		{"DeleteForce", testDeleteForce},
+		{"UpdateReplacesDependencies", testUpdateReplacesDependencies},
+		{"UnknownRef", testUnknownRef},
+		{"BatchUnknownRef", testBatchUnknownRef},
+		{"BatchPartial", testBatchPartial},
+		{"BatchAtomic", testBatchAtomic},
+		{"BatchExplicitID", testBatchExplicitID},
+		{"BatchUnknownOp", testBatchUnknownOp},
+		{"Concurrent", testConcurrent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepo(t))
+		})
+	}
+}
+
+func testCreateGet(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	q := types.Query{"tags": []any{"golang", "db"}, "date_from": "{{today-7d}}"}
+
+	f, err := r.Create(ctx, "Go", q)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if f.ID == uuid.Nil {
+		t.Fatal("Create returned nil ID")
+	}
+	if f.Name != "Go" {
+		t.Errorf("Create name = %q, want %q", f.Name, "Go")
+	}
+	if f.CreatedAt.IsZero() {
+		t.Error("Create returned zero created_at")
+	}
+
+	got, err := r.Get(ctx, f.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.ID != f.ID || got.Name != "Go" {
+		t.Errorf("Get = %s %q, want %s %q", got.ID, got.Name, f.ID, "Go")
+	}
+	assertQuery(t, got.Query, q)
+}
+
+func testGetNotFound(t *testing.T, r repository.Repository) {
+	_, err := r.Get(context.Background(), uuid.New())
+	assertErr(t, err, repository.ErrNotFound)
+}
+
+func testListEmpty(t *testing.T, r repository.Repository) {
+	list, err := r.List(context.Background())
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 0 {
+		t.Errorf("List = %d items, want 0", len(list))
+	}
+}
+
+func testListOrder(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	want := make([]string, 0, 5)
+	for i := 0; i < 5; i++ {
+		f := mustCreate(t, r, "f", types.Query{"n": "x"})
+		want = append(want, f.ID.String())
+	}
+	sort.Strings(want)
+
+	list, err := r.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != len(want) {
+		t.Fatalf("List = %d items, want %d", len(list), len(want))
+	}
+	for i, item := range list {
+		if item.ID.String() != want[i] {
+			t.Errorf("List[%d] = %s, want %s (ordered by id)", i, item.ID, want[i])
+		}
+		assertQuery(t, item.Query, types.Query{"n": "x"})
+	}
+}
+
+func testUpdate(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := mustCreate(t, r, "Go", types.Query{"tags": []any{"golang"}})
+
+	q := types.Query{"tags": []any{"db"}, "text": "postgres"}
+	upd, err := r.Update(ctx, f.ID, q)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if upd.Name != "Go" {
+		t.Errorf("Update changed name to %q", upd.Name)
+	}
+	assertQuery(t, upd.Query, q)
+
+	got, err := r.Get(ctx, f.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	assertQuery(t, got.Query, q)
+}
+
+func testUpdateNotFound(t *testing.T, r repository.Repository) {
+	_, err := r.Update(context.Background(), uuid.New(), types.Query{})
+	assertErr(t, err, repository.ErrNotFound)
+}
+
+func testDelete(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := mustCreate(t, r, "Go", types.Query{})
+
+	if err := r.Delete(ctx, f.ID, false); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	_, err := r.Get(ctx, f.ID)
+	assertErr(t, err, repository.ErrNotFound)
+}
+
+func testDeleteNotFound(t *testing.T, r repository.Repository) {
+	err := r.Delete(context.Background(), uuid.New(), false)
+	assertErr(t, err, repository.ErrNotFound)
+	err = r.Delete(context.Background(), uuid.New(), true)
+	assertErr(t, err, repository.ErrNotFound)
+}
+
+func testDependents(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	base := mustCreate(t, r, "base", types.Query{"tags": []any{"golang"}})
+	a := mustCreate(t, r, "a", types.Query(ref(base.ID)))
+	b := mustCreate(t, r, "b", types.Query{compose.KeyAllOf: []any{ref(base.ID), ref(a.ID)}})
+
+	deps, err := r.Dependents(ctx, base.ID)
+	if err != nil {
+		t.Fatalf("Dependents: %v", err)
+	}
+	want := []uuid.UUID{a.ID, b.ID}
+	sort.Slice(want, func(i, j int) bool { return want[i].String() < want[j].String() })
+	assertIDs(t, deps, want)
+
+	deps, err = r.Dependents(ctx, b.ID)
+	if err != nil {
+		t.Fatalf("Dependents: %v", err)
+	}
+	assertIDs(t, deps, nil)
+}
+
+func testDeleteWithDependents(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	base := mustCreate(t, r, "base", types.Query{})
+	child := mustCreate(t, r, "child", types.Query(ref(base.ID)))
+
+	err := r.Delete(ctx, base.ID, false)
+	assertErr(t, err, repository.ErrHasDependents)
+	var de *repository.DependentsError
+	if !errors.As(err, &de) {
+		t.Fatalf("err = %T, want *repository.DependentsError", err)
+	}
+	assertIDs(t, de.Dependents, []uuid.UUID{child.ID})
+	if _, err := r.Get(ctx, base.ID); err != nil {
+		t.Errorf("Get after refused delete: %v", err)
+	}
+}
+
+func testDeleteForce(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	base := mustCreate(t, r, "base", types.Query{})
+	child := mustCreate(t, r, "child", types.Query(ref(base.ID)))
+
+	if err := r.Delete(ctx, base.ID, true); err != nil {
+		t.Fatalf("Delete force: %v", err)
+	}
+	deps, err := r.Dependents(ctx, base.ID)
+	if err != nil {
+		t.Fatalf("Dependents: %v", err)
+	}
+	assertIDs(t, deps, nil)
+	if _, err := r.Get(ctx, child.ID); err != nil {
+		t.Errorf("dependent filter removed with forced delete: %v", err)
+	}
+}
+
+func testUpdateReplacesDependencies(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	base := mustCreate(t, r, "base", types.Query{})
+	child := mustCreate(t, r, "child", types.Query(ref(base.ID)))
+
+	if _, err := r.Update(ctx, child.ID, types.Query{"tags": []any{"x"}}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	deps, err := r.Dependents(ctx, base.ID)
+	if err != nil {
+		t.Fatalf("Dependents: %v", err)
+	}
+	assertIDs(t, deps, nil)
+	if err := r.Delete(ctx, base.ID, false); err != nil {
+		t.Errorf("Delete after dependency removed: %v", err)
+	}
+}
+
+// testUnknownRef: ссылка на несуществующий фильтр отклоняется, а ссылка вперёд
+// внутри пакета — нет.
+func testUnknownRef(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+
+	_, err := r.Create(ctx, "dangling", types.Query(ref(uuid.New())))
+	assertErr(t, err, repository.ErrUnknownRef)
+
+	base := uuid.New()
+	res, err := r.Batch(ctx, []repository.BatchOp{
+		{Kind: repository.BatchCreate, Name: "child", Query: types.Query(ref(base))},
+		{Kind: repository.BatchCreate, ID: base, Name: "base", Query: types.Query{}},
+	}, true)
+	if err != nil || res[0].Err != nil || res[1].Err != nil {
+		t.Fatalf("Batch with forward ref = %+v, %v", res, err)
+	}
+
+	_, err = r.Update(ctx, res[0].Filter.ID, types.Query{compose.KeyAllOf: []any{ref(uuid.New())}})
+	assertErr(t, err, repository.ErrUnknownRef)
+
+	items, err := r.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(items) != 2 {
+		t.Errorf("List = %d filters, want 2", len(items))
+	}
+}
+
+// testBatchUnknownRef: в неатомарном пакете повисшая ссылка отклоняет только
+// свою операцию, в том числе когда цель должна была создаться позже, но не создалась.
+func testBatchUnknownRef(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	existing := mustCreate(t, r, "existing", types.Query{})
+	target, ok := uuid.New(), uuid.New()
+
+	res, err := r.Batch(ctx, []repository.BatchOp{
+		{Kind: repository.BatchCreate, Name: "child", Query: types.Query(ref(target))},
+		{Kind: repository.BatchCreate, ID: target, Name: "target", Query: types.Query{compose.KeyRef: 42}},
+		{Kind: repository.BatchCreate, ID: ok, Name: "ok", Query: types.Query(ref(existing.ID))},
+		{Kind: repository.BatchCreate, Name: "dangling", Query: types.Query(ref(uuid.New()))},
+		{Kind: repository.BatchUpdate, ID: existing.ID, Query: types.Query(ref(uuid.New()))},
+	}, false)
+	if err != nil {
+		t.Fatalf("Batch: %v", err)
+	}
+	assertErr(t, res[0].Err, repository.ErrUnknownRef)
+	assertErr(t, res[1].Err, compose.ErrInvalidRef)
+	if res[2].Err != nil || res[2].Filter == nil {
+		t.Errorf("result 2 = %+v", res[2])
+	}
+	assertErr(t, res[3].Err, repository.ErrUnknownRef)
+	assertErr(t, res[4].Err, repository.ErrUnknownRef)
+
+	items, err := r.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(items) != 2 {
+		t.Errorf("List = %+v, want existing and ok", items)
+	}
+	got, err := r.Get(ctx, existing.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	assertQuery(t, got.Query, types.Query{})
+}
+
+func testBatchPartial(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	existing := mustCreate(t, r, "existing", types.Query{})
+	missing := uuid.New()
+
+	res, err := r.Batch(ctx, []repository.BatchOp{
+		{Kind: repository.BatchCreate, Name: "new", Query: types.Query{"a": "b"}},
+		{Kind: repository.BatchUpdate, ID: missing, Query: types.Query{}},
+		{Kind: repository.BatchUpdate, ID: existing.ID, Name: "renamed", Query: types.Query{"c": "d"}},
+	}, false)
+	if err != nil {
+		t.Fatalf("Batch: %v", err)
+	}
+	if len(res) != 3 {
+		t.Fatalf("Batch returned %d results, want 3", len(res))
+	}
+	if res[0].Err != nil || res[0].Filter == nil {
+		t.Fatalf("create result = %+v", res[0])
+	}
+	assertErr(t, res[1].Err, repository.ErrNotFound)
+	if res[2].Err != nil || res[2].Filter.Name != "renamed" {
+		t.Errorf("update result = %+v", res[2])
+	}
+
+	if _, err := r.Get(ctx, res[0].Filter.ID); err != nil {
+		t.Errorf("created filter not persisted: %v", err)
+	}
+	got, err := r.Get(ctx, existing.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Name != "renamed" {
+		t.Errorf("name = %q, want %q", got.Name, "renamed")
+	}
+	assertQuery(t, got.Query, types.Query{"c": "d"})
+}
+
+func testBatchAtomic(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	existing := mustCreate(t, r, "existing", types.Query{"a": "b"})
+
+	res, err := r.Batch(ctx, []repository.BatchOp{
+		{Kind: repository.BatchCreate, Name: "new", Query: types.Query{}},
+		{Kind: repository.BatchUpdate, ID: existing.ID, Query: types.Query{"c": "d"}},
+		{Kind: repository.BatchDelete, ID: uuid.New()},
+		{Kind: repository.BatchDelete, ID: existing.ID},
+	}, true)
+	if err != nil {
+		t.Fatalf("Batch: %v", err)
+	}
+	if len(res) != 4 {
+		t.Fatalf("Batch returned %d results, want 4", len(res))
+	}
+	assertErr(t, res[0].Err, repository.ErrRolledBack)
+	assertErr(t, res[1].Err, repository.ErrRolledBack)
+	assertErr(t, res[2].Err, repository.ErrNotFound)
+	assertErr(t, res[3].Err, repository.ErrSkipped)
+	for i, item := range res {
+		if item.Filter != nil {
+			t.Errorf("result %d of aborted batch has filter %s", i, item.Filter.ID)
+		}
+	}
+
+	list, err := r.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("List after rollback = %d items, want 1", len(list))
+	}
+	assertQuery(t, list[0].Query, types.Query{"a": "b"})
+}
+
+func testBatchExplicitID(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	id := uuid.New()
+
+	res, err := r.Batch(ctx, []repository.BatchOp{
+		{Kind: repository.BatchCreate, ID: id, Name: "first", Query: types.Query{}},
+		{Kind: repository.BatchCreate, ID: id, Name: "second", Query: types.Query{}},
+	}, false)
+	if err != nil {
+		t.Fatalf("Batch: %v", err)
+	}
+	if res[0].Err != nil || res[0].Filter.ID != id {
+		t.Fatalf("create with explicit ID = %+v", res[0])
+	}
+	assertErr(t, res[1].Err, repository.ErrAlreadyExists)
+
+	got, err := r.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Name != "first" {
+		t.Errorf("name = %q, want %q", got.Name, "first")
+	}
+}
+
+func testBatchUnknownOp(t *testing.T, r repository.Repository) {
+	res, err := r.Batch(context.Background(), []repository.BatchOp{{Kind: "upsert"}}, false)
+	if err != nil {
+		t.Fatalf("Batch: %v", err)
+	}
+	assertErr(t, res[0].Err, repository.ErrUnknownBatchOp)
+}
+
+func testConcurrent(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	const n = 16
+
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		ids []uuid.UUID
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f, err := r.Create(ctx, "c", types.Query{"v": "0"})
+			if err != nil {
+				t.Errorf("Create: %v", err)
+				return
+			}
+			if _, err := r.Update(ctx, f.ID, types.Query{"v": "1"}); err != nil {
+				t.Errorf("Update: %v", err)
+			}
+			if _, err := r.Get(ctx, f.ID); err != nil {
+				t.Errorf("Get: %v", err)
+			}
+			if _, err := r.List(ctx); err != nil {
+				t.Errorf("List: %v", err)
+			}
+			mu.Lock()
+			ids = append(ids, f.ID)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if t.Failed() {
+		return
+	}
+
+	list, err := r.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != n {
+		t.Fatalf("List = %d items, want %d", len(list), n)
+	}
+	for _, item := range list {
+		assertQuery(t, item.Query, types.Query{"v": "1"})
+	}
+
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id uuid.UUID) {
+			defer wg.Done()
+			if err := r.Delete(ctx, id, false); err != nil {
+				t.Errorf("Delete: %v", err)
+			}
+		}(id)
+	}
+	wg.Wait()
+
+	list, err = r.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 0 {
+		t.Errorf("List after deletes = %d items, want 0", len(list))
+	}
+}
+
+func mustCreate(t *testing.T, r repository.Repository, name string, q types.Query) *models.Filter {
+	t.Helper()
+	f, err := r.Create(context.Background(), name, q)
+	if err != nil {
+		t.Fatalf("Create %q: %v", name, err)
+	}
+	return f
+}
+
+func ref(id uuid.UUID) map[string]any {
+	return map[string]any{compose.KeyRef: id.String()}
+}
+
+func assertErr(t *testing.T, got, want error) {
+	t.Helper()
+	if !errors.Is(got, want) {
+		t.Errorf("err = %v, want %v", got, want)
+	}
+}
+
+// assertQuery сравнивает запросы по JSON: хранилища возвращают числа и массивы
+// в том виде, в каком их декодирует encoding/json.
+func assertQuery(t *testing.T, got, want types.Query) {
+	t.Helper()
+	g, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("marshal got: %v", err)
+	}
+	w, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("marshal want: %v", err)
+	}
+	if string(g) != string(w) {
+		t.Errorf("query = %s, want %s", g, w)
+	}
+}
+
+func assertIDs(t *testing.T, got, want []uuid.UUID) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("ids = %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("ids = %v, want %v", got, want)
+			return
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	reform "gopkg.in/reform.v1"
+)
+
+// SQLiteRepository хранит фильтры в SQLite для одноузловых установок.
+// ID генерируются на стороне приложения: в SQLite нет gen_random_uuid().
+type SQLiteRepository struct{ reformRepository }
+
+func NewSQLiteRepository(db *reform.DB) *SQLiteRepository {
+	return &SQLiteRepository{reformRepository{db: db, newID: uuid.New}}
+}
@@ -0,0 +1,18 @@
+package repository_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"search-filter/pkg/repository"
+	"search-filter/pkg/repository/repotest"
+	"search-filter/pkg/storage"
+)
+
+func TestSQLiteRepository(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.Repository {
+		dbs := storage.MustInitSQLite(filepath.Join(t.TempDir(), "filters.db"), nil)
+		t.Cleanup(func() { dbs.SQL.Close() })
+		return repository.NewSQLiteRepository(dbs.Reform)
+	})
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"search-filter/pkg/models"
+	"search-filter/pkg/repository"
+	"search-filter/pkg/types"
+
+	"github.com/google/uuid"
+)
+
+const MaxBatchSize = 500
+
+var ErrAborted = errors.New("aborted")
+
+type BatchResult struct {
+	Filter *models.Filter
+	Err    error
+}
+
+// Batch проверяет операции и выполняет их одной транзакцией. В атомарном режиме
+// невалидная операция отклоняет весь пакет до обращения к хранилищу.
+// Ссылки $ref раскрываются так же, как в Create и Update, но по состоянию после
+// пакета: цели могут создаваться, меняться и удаляться в нём же.
+func (s *service) Batch(ctx context.Context, ops []repository.BatchOp, atomic bool) ([]BatchResult, error) {
+	if len(ops) == 0 {
+		return nil, Invalid(CodeValidation, "batch is empty", FieldError{Field: "operations", Message: "must not be empty"})
+	}
+	if len(ops) > MaxBatchSize {
+		detail := fmt.Sprintf("batch exceeds %d operations", MaxBatchSize)
+		return nil, Invalid(CodeValidation, detail, FieldError{Field: "operations", Message: detail})
+	}
+
+	res := make([]BatchResult, len(ops))
+	failed := false
+	for i, op := range ops {
+		if err := validateBatchOp(op); err != nil {
+			res[i].Err = err
+			failed = true
+		}
+	}
+	if !failed || !atomic {
+		failed = s.resolveBatch(ctx, ops, res, atomic) || failed
+	}
+
+	if failed && atomic {
+		for i := range res {
+			if res[i].Err == nil {
+				res[i].Err = fmt.Errorf("%w: another operation in the atomic batch is invalid", ErrAborted)
+			}
+		}
+		return res, nil
+	}
+
+	valid := make([]repository.BatchOp, 0, len(ops))
+	idx := make([]int, 0, len(ops))
+	for i, op := range ops {
+		if res[i].Err == nil {
+			valid = append(valid, op)
+			idx = append(idx, i)
+		}
+	}
+	if len(valid) == 0 {
+		return res, nil
+	}
+
+	out, err := s.repo.Batch(ctx, valid, atomic)
+	if errors.Is(err, repository.ErrUnknownRef) {
+		// Повисшие ссылки хранилище возвращает по операциям; сюда попадает только
+		// цель, удалённая параллельным запросом до фиксации пакета.
+		return nil, unknownRef(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for j, r := range out {
+		res[idx[j]] = BatchResult{Filter: r.Filter, Err: batchError(valid[j], r.Err)}
+	}
+	return res, nil
+}
+
+// resolveBatch раскрывает ссылки операций create и update, ещё не отклонённых
+// в res, и записывает ошибки в res. Отклонённая операция меняет состояние после
+// пакета, поэтому в неатомарном режиме проверка повторяется, пока появляются
+// новые ошибки. Возвращает, были ли ошибки.
+func (s *service) resolveBatch(ctx context.Context, ops []repository.BatchOp, res []BatchResult, atomic bool) bool {
+	failed := false
+	for changed := true; changed; {
+		changed = false
+		state := batchState(ops, res)
+		lookup := func(ctx context.Context, id uuid.UUID) (types.Query, error) {
+			if q, ok := state[id]; ok {
+				if q == nil {
+					return nil, repository.ErrNotFound
+				}
+				return q, nil
+			}
+			return s.lookup(ctx, id)
+		}
+		for i, op := range ops {
+			if res[i].Err != nil || op.Kind == repository.BatchDelete {
+				continue
+			}
+			if _, err := s.resolveWith(ctx, op.ID, op.Query, lookup); err != nil {
+				res[i].Err = err
+				failed, changed = true, true
+			}
+		}
+		if atomic {
+			break
+		}
+	}
+	return failed
+}
+
+// batchState — запросы фильтров после применения операций пакета, не отклонённых
+// в res. Удалённые фильтры отображаются в nil.
+func batchState(ops []repository.BatchOp, res []BatchResult) map[uuid.UUID]types.Query {
+	state := map[uuid.UUID]types.Query{}
+	for i, op := range ops {
+		if res[i].Err != nil || op.ID == uuid.Nil {
+			continue
+		}
+		switch op.Kind {
+		case repository.BatchCreate, repository.BatchUpdate:
+			state[op.ID] = op.Query
+		case repository.BatchDelete:
+			state[op.ID] = nil
+		}
+	}
+	return state
+}
+
+func validateBatchOp(op repository.BatchOp) error {
+	switch op.Kind {
+	case repository.BatchCreate:
+		if op.Name == "" {
+			return Invalid(CodeValidation, "name is required", FieldError{Field: "name", Message: "is required"})
+		}
+		if len(op.Query) == 0 {
+			return Invalid(CodeValidation, "query is required", FieldError{Field: "query", Message: "is required"})
+		}
+	case repository.BatchUpdate:
+		if op.ID == uuid.Nil {
+			return invalidID("id")
+		}
+		if len(op.Query) == 0 {
+			return Invalid(CodeValidation, "query is required", FieldError{Field: "query", Message: "is required"})
+		}
+	case repository.BatchDelete:
+		if op.ID == uuid.Nil {
+			return invalidID("id")
+		}
+	default:
+		return Invalid(CodeValidation, fmt.Sprintf("unknown op %q", op.Kind), FieldError{Field: "op", Message: "must be create, update or delete"})
+	}
+	return nil
+}
+
+func batchError(op repository.BatchOp, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, repository.ErrNotFound):
+		return filterNotFound(op.ID)
+	case errors.Is(err, repository.ErrAlreadyExists):
+		return Conflict(CodeConflict, "filter %s already exists", op.ID)
+	}
+	return FromRepository(err)
+}
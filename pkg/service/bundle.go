@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"search-filter/pkg/bundle"
+	"search-filter/pkg/compose"
+	"search-filter/pkg/repository"
+
+	"github.com/google/uuid"
+)
+
+// ConflictStrategy определяет, что делать с фильтром из выгрузки, чей ID уже занят.
+type ConflictStrategy string
+
+const (
+	ConflictSkip      ConflictStrategy = "skip"
+	ConflictOverwrite ConflictStrategy = "overwrite"
+	ConflictRename    ConflictStrategy = "rename"
+)
+
+const renameSuffix = " (imported)"
+
+type ImportAction string
+
+const (
+	ImportCreated ImportAction = "created"
+	ImportUpdated ImportAction = "updated"
+	ImportSkipped ImportAction = "skipped"
+)
+
+type ImportItem struct {
+	SourceID uuid.UUID    `json:"source_id" yaml:"source_id"`
+	ID       uuid.UUID    `json:"id"        yaml:"id"`
+	Name     string       `json:"name"      yaml:"name"`
+	Action   ImportAction `json:"action"    yaml:"action"`
+}
+
+type ImportReport struct {
+	Created int          `json:"created" yaml:"created"`
+	Updated int          `json:"updated" yaml:"updated"`
+	Skipped int          `json:"skipped" yaml:"skipped"`
+	Items   []ImportItem `json:"items"   yaml:"items"`
+}
+
+// Export выгружает фильтры с указанными ID или все, если ids пуст.
+func (s *service) Export(ctx context.Context, ids []uuid.UUID) (*bundle.Bundle, error) {
+	b := &bundle.Bundle{Version: bundle.Version, ExportedAt: time.Now().UTC(), Filters: []bundle.Filter{}}
+
+	if len(ids) == 0 {
+		items, err := s.repo.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, it := range items {
+			b.Filters = append(b.Filters, bundle.Filter{ID: it.ID, Name: it.Name, Query: it.Query})
+		}
+		return b, nil
+	}
+
+	for _, id := range ids {
+		f, err := s.Get(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("export %s: %w", id, err)
+		}
+		b.Filters = append(b.Filters, bundle.Filter{ID: f.ID, Name: f.Name, Query: f.Query})
+	}
+	return b, nil
+}
+
+// Import загружает выгрузку одной атомарной транзакцией. Фильтры без конфликта
+// создаются с исходными ID; при rename конфликтующий фильтр получает новый ID,
+// и ссылки $ref внутри выгрузки переписываются на него.
+func (s *service) Import(ctx context.Context, b *bundle.Bundle, strategy ConflictStrategy) (*ImportReport, error) {
+	switch strategy {
+	case ConflictSkip, ConflictOverwrite, ConflictRename:
+	default:
+		return nil, Invalid(CodeValidation, fmt.Sprintf("unknown conflict strategy %q", strategy),
+			FieldError{Field: "strategy", Message: "must be skip, overwrite or rename"})
+	}
+
+	existing := map[uuid.UUID]struct{}{}
+	items, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, it := range items {
+		existing[it.ID] = struct{}{}
+	}
+
+	report := &ImportReport{Items: make([]ImportItem, 0, len(b.Filters))}
+	remap := map[uuid.UUID]uuid.UUID{}
+	seen := map[uuid.UUID]struct{}{}
+	for i, f := range b.Filters {
+		if f.Name == "" || len(f.Query) == 0 {
+			return nil, Invalid(CodeValidation, fmt.Sprintf("filter %d: name and query are required", i),
+				FieldError{Field: fmt.Sprintf("filters[%d]", i), Message: "name and query are required"})
+		}
+		if _, err := compose.Refs(f.Query); err != nil {
+			return nil, Invalid(CodeInvalidQuery, fmt.Sprintf("filter %d: %s", i, err),
+				FieldError{Field: fmt.Sprintf("filters[%d].query", i), Message: err.Error()})
+		}
+		if f.ID == uuid.Nil {
+			f.ID = uuid.New()
+		}
+		if _, dup := seen[f.ID]; dup {
+			return nil, Invalid(CodeValidation, fmt.Sprintf("duplicate id %s in bundle", f.ID),
+				FieldError{Field: fmt.Sprintf("filters[%d].id", i), Message: "duplicate id"})
+		}
+		seen[f.ID] = struct{}{}
+
+		item := ImportItem{SourceID: f.ID, ID: f.ID, Name: f.Name, Action: ImportCreated}
+		if _, ok := existing[f.ID]; ok {
+			switch strategy {
+			case ConflictSkip:
+				item.Action = ImportSkipped
+			case ConflictOverwrite:
+				item.Action = ImportUpdated
+			case ConflictRename:
+				item.ID = uuid.New()
+				item.Name = f.Name + renameSuffix
+				remap[f.ID] = item.ID
+			}
+		}
+		report.Items = append(report.Items, item)
+	}
+
+	var (
+		ops []repository.BatchOp
+		src []int
+	)
+	for i, item := range report.Items {
+		q, err := compose.RewriteRefs(b.Filters[i].Query, remap)
+		if err != nil {
+			return nil, Invalid(CodeInvalidQuery, err.Error())
+		}
+		switch item.Action {
+		case ImportCreated:
+			ops = append(ops, repository.BatchOp{Kind: repository.BatchCreate, ID: item.ID, Name: item.Name, Query: q})
+			report.Created++
+		case ImportUpdated:
+			ops = append(ops, repository.BatchOp{Kind: repository.BatchUpdate, ID: item.ID, Name: item.Name, Query: q})
+			report.Updated++
+		case ImportSkipped:
+			report.Skipped++
+			continue
+		}
+		src = append(src, i)
+	}
+	if len(ops) == 0 {
+		return report, nil
+	}
+
+	// Ссылки раскрываются уже переписанными, по состоянию после импорта: цель
+	// должна быть в выгрузке или в БД, циклы отклоняются так же, как в Create.
+	resolved := make([]BatchResult, len(ops))
+	if s.resolveBatch(ctx, ops, resolved, true) {
+		for j, r := range resolved {
+			if r.Err != nil {
+				i := src[j]
+				return nil, Invalid(CodeInvalidQuery, fmt.Sprintf("filter %d: %s", i, r.Err),
+					FieldError{Field: fmt.Sprintf("filters[%d].query", i), Message: r.Err.Error()})
+			}
+		}
+	}
+
+	res, err := s.repo.Batch(ctx, ops, true)
+	if errors.Is(err, repository.ErrUnknownRef) {
+		return nil, unknownRef(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for j, r := range res {
+		if r.Err != nil && !errors.Is(r.Err, repository.ErrRolledBack) && !errors.Is(r.Err, repository.ErrSkipped) {
+			if errors.Is(r.Err, repository.ErrNotFound) {
+				return nil, Conflict(CodeConflict, "import: %s", r.Err)
+			}
+			return nil, batchError(ops[j], r.Err)
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"search-filter/pkg/repository"
+)
+
+var (
+	ErrNotFound           = errors.New("not found")
+	ErrValidation         = errors.New("validation error")
+	ErrConflict           = errors.New("conflict")
+	ErrForbidden          = errors.New("forbidden")
+	ErrPreconditionFailed = errors.New("precondition failed")
+	ErrRateLimited        = errors.New("rate limited")
+	ErrNoBackend          = errors.New("search backend is not configured")
+	ErrBackend            = errors.New("search backend error")
+)
+
+// Коды ошибок — стабильные машиночитаемые значения поля code в ответах API.
+// Текст ошибок может меняться, коды — нет.
+const (
+	CodeNotFound           = "not_found"
+	CodeFilterNotFound     = "filter_not_found"
+	CodeValidation         = "validation_failed"
+	CodeInvalidID          = "invalid_id"
+	CodeInvalidQuery       = "invalid_query"
+	CodeRenderFailed       = "render_failed"
+	CodeInvalidCursor      = "invalid_cursor"
+	CodeUnsupportedQuery   = "unsupported_query"
+	CodeConflict           = "conflict"
+	CodeFilterReferenced   = "filter_referenced"
+	CodeAborted            = "aborted"
+	CodeForbidden          = "forbidden"
+	CodePreconditionFailed = "precondition_failed"
+	CodeRateLimited        = "rate_limited"
+	CodeNoBackend          = "search_backend_not_configured"
+	CodeBackend            = "search_backend_unavailable"
+	CodeInternal           = "internal_error"
+)
+
+// FieldError указывает на конкретное невалидное поле входных данных.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// Error — доменная ошибка сервиса. Kind — одна из ErrNotFound, ErrValidation,
+// ErrConflict, ErrForbidden, ErrPreconditionFailed, ErrRateLimited, поэтому
+// errors.Is(err, ErrValidation) и т.п. работает и для типизированных ошибок.
+type Error struct {
+	Kind   error
+	Code   string
+	Detail string
+	// Fields — невалидные поля для ошибок валидации.
+	Fields []FieldError
+	// RetryAfter — через сколько повторить запрос для ErrRateLimited; 0 — не указано.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.Detail == "" {
+		return e.Kind.Error()
+	}
+	return e.Kind.Error() + ": " + e.Detail
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func NotFound(code, format string, args ...any) *Error {
+	return &Error{Kind: ErrNotFound, Code: code, Detail: fmt.Sprintf(format, args...)}
+}
+
+// Invalid — ошибка валидации; detail описывает её целиком, fields уточняют поля.
+func Invalid(code, detail string, fields ...FieldError) *Error {
+	return &Error{Kind: ErrValidation, Code: code, Detail: detail, Fields: fields}
+}
+
+func Conflict(code, format string, args ...any) *Error {
+	return &Error{Kind: ErrConflict, Code: code, Detail: fmt.Sprintf(format, args...)}
+}
+
+func Forbidden(code, format string, args ...any) *Error {
+	return &Error{Kind: ErrForbidden, Code: code, Detail: fmt.Sprintf(format, args...)}
+}
+
+func PreconditionFailed(code, format string, args ...any) *Error {
+	return &Error{Kind: ErrPreconditionFailed, Code: code, Detail: fmt.Sprintf(format, args...)}
+}
+
+func RateLimited(code string, retryAfter time.Duration, format string, args ...any) *Error {
+	return &Error{Kind: ErrRateLimited, Code: code, Detail: fmt.Sprintf(format, args...), RetryAfter: retryAfter}
+}
+
+// invalidID — ошибка для нулевого или отсутствующего ID фильтра.
+func invalidID(field string) *Error {
+	return Invalid(CodeInvalidID, "invalid id", FieldError{Field: field, Message: "must be a non-nil UUID"})
+}
+
+func filterNotFound(id fmt.Stringer) *Error {
+	return NotFound(CodeFilterNotFound, "filter %s", id)
+}
+
+func unknownRef(err error) *Error {
+	return Invalid(CodeInvalidQuery, err.Error(), FieldError{Field: "query", Message: err.Error()})
+}
+
+// FromRepository переводит ошибку-сентинел хранилища в типизированную ошибку
+// сервиса; остальные ошибки возвращает как есть. Сервис сам сопоставляет
+// сентинелы с контекстом операции, это страховка для тех, что дошли до
+// обработчика напрямую.
+func FromRepository(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return NotFound(CodeFilterNotFound, "%s", err)
+	case errors.Is(err, repository.ErrAlreadyExists):
+		return Conflict(CodeConflict, "%s", err)
+	case errors.Is(err, repository.ErrHasDependents):
+		return Conflict(CodeFilterReferenced, "filter is referenced by other filters")
+	case errors.Is(err, repository.ErrUnknownRef):
+		return unknownRef(err)
+	case errors.Is(err, repository.ErrUnknownBatchOp):
+		return Invalid(CodeValidation, err.Error(), FieldError{Field: "op", Message: "must be create, update or delete"})
+	case errors.Is(err, repository.ErrRolledBack), errors.Is(err, repository.ErrSkipped):
+		return fmt.Errorf("%w: %s", ErrAborted, err)
+	}
+	return err
+}
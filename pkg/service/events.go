@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"search-filter/pkg/models"
+	"search-filter/pkg/repository"
+)
+
+// MaxEventsPage — наибольшее число событий за один запрос.
+const MaxEventsPage = 1000
+
+// Events отдаёт ленту изменений фильтров для потребителей, опрашивающих её сами.
+type Events interface {
+	// List возвращает до limit событий с id больше since.
+	List(ctx context.Context, since int64, limit int) ([]models.Event, error)
+	// Latest возвращает id последнего события — точку, с которой начинается
+	// подписка без Last-Event-ID.
+	Latest(ctx context.Context) (int64, error)
+}
+
+type events struct {
+	log repository.EventLog
+}
+
+func NewEventsService(log repository.EventLog) (Events, error) {
+	if log == nil {
+		return nil, fmt.Errorf("NewEventsService: log is nil")
+	}
+	return &events{log: log}, nil
+}
+
+func (e *events) List(ctx context.Context, since int64, limit int) ([]models.Event, error) {
+	if since < 0 {
+		return nil, Invalid(CodeValidation, "since must be >= 0", FieldError{Field: "since", Message: "must be >= 0"})
+	}
+	if limit <= 0 || limit > MaxEventsPage {
+		detail := fmt.Sprintf("limit must be between 1 and %d", MaxEventsPage)
+		return nil, Invalid(CodeValidation, detail, FieldError{Field: "limit", Message: detail})
+	}
+	return e.log.Events(ctx, since, limit)
+}
+
+func (e *events) Latest(ctx context.Context) (int64, error) {
+	return e.log.LastEventID(ctx)
+}
@@ -4,8 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
+	"search-filter/pkg/backend"
+	"search-filter/pkg/bundle"
+	"search-filter/pkg/cache"
+	"search-filter/pkg/compose"
+	"search-filter/pkg/elastic"
 	"search-filter/pkg/models"
 	"search-filter/pkg/placeholder"
 	"search-filter/pkg/repository"
@@ -14,27 +20,55 @@ import (
 	"github.com/google/uuid"
 )
 
-var (
-	ErrNotFound   = errors.New("not found")
-	ErrValidation = errors.New("validation error")
-)
-
 type Filters interface {
 	Create(ctx context.Context, name string, query types.Query) (*models.Filter, error)
 	List(ctx context.Context) ([]models.FilterListItem, error)
 	Get(ctx context.Context, id uuid.UUID) (*models.Filter, error)
 	Update(ctx context.Context, id uuid.UUID, query types.Query) (*models.Filter, error)
-	Delete(ctx context.Context, id uuid.UUID) error
+	Delete(ctx context.Context, id uuid.UUID, force bool) error
 	Apply(ctx context.Context, id uuid.UUID) (types.Query, error)
+	Results(ctx context.Context, id uuid.UUID, page backend.Page) ([]backend.Document, string, error)
+	Batch(ctx context.Context, ops []repository.BatchOp, atomic bool) ([]BatchResult, error)
+	Export(ctx context.Context, ids []uuid.UUID) (*bundle.Bundle, error)
+	Import(ctx context.Context, b *bundle.Bundle, strategy ConflictStrategy) (*ImportReport, error)
 }
 
 type service struct {
 	repo          repository.Repository
 	loc           *time.Location
 	currentUserID int64
+	backend       backend.Backend
+	cache         *cache.Cache
+	observeRender RenderObserver
+}
+
+type Option func(*service)
+
+// WithBackend подключает поисковый бэкенд для выполнения фильтров.
+func WithBackend(b backend.Backend) Option {
+	return func(s *service) { s.backend = b }
+}
+
+// WithCache кэширует отрендеренные запросы Apply по (фильтр, пользователь, день):
+// плейсхолдеры дат меняются раз в сутки. c должен быть тем же кэшем, что у
+// repository.CachedRepository, — тогда изменения фильтров инвалидируют и результаты.
+func WithCache(c *cache.Cache) Option {
+	return func(s *service) { s.cache = c }
 }
 
-func NewFiltersService(repo repository.Repository, loc *time.Location, currentUserID int64) (Filters, error) {
+// RenderObserver получает длительность и ошибку каждого рендеринга плейсхолдеров.
+type RenderObserver func(d time.Duration, err error)
+
+// WithRenderObserver сообщает o о каждом рендеринге плейсхолдеров в Apply.
+func WithRenderObserver(o RenderObserver) Option {
+	return func(s *service) { s.observeRender = o }
+}
+
+func applyCacheKey(id uuid.UUID, user int64, now time.Time) string {
+	return fmt.Sprintf("apply:%s:%d:%s", id, user, now.Format("2006-01-02"))
+}
+
+func NewFiltersService(repo repository.Repository, loc *time.Location, currentUserID int64, opts ...Option) (Filters, error) {
 	if repo == nil {
 		return nil, fmt.Errorf("NewFiltersService: repo is nil")
 	}
@@ -44,11 +78,23 @@ func NewFiltersService(repo repository.Repository, loc *time.Location, currentUs
 	if currentUserID <= 0 {
 		return nil, fmt.Errorf("NewFiltersService: currentUserID must be > 0, got %d", currentUserID)
 	}
-	return &service{repo: repo, loc: loc, currentUserID: currentUserID}, nil
+	s := &service{repo: repo, loc: loc, currentUserID: currentUserID}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
 }
 
 func (s *service) Create(ctx context.Context, name string, query types.Query) (*models.Filter, error) {
-	return s.repo.Create(ctx, name, query)
+	if _, err := s.resolve(ctx, uuid.Nil, query); err != nil {
+		return nil, err
+	}
+	f, err := s.repo.Create(ctx, name, query)
+	if errors.Is(err, repository.ErrUnknownRef) {
+		// Фильтр, на который ссылается запрос, удалили после resolve.
+		return nil, unknownRef(err)
+	}
+	return f, err
 }
 
 func (s *service) List(ctx context.Context) ([]models.FilterListItem, error) {
@@ -57,11 +103,11 @@ func (s *service) List(ctx context.Context) ([]models.FilterListItem, error) {
 
 func (s *service) Get(ctx context.Context, id uuid.UUID) (*models.Filter, error) {
 	if id == uuid.Nil {
-		return nil, fmt.Errorf("%w: invalid id", ErrValidation)
+		return nil, invalidID("id")
 	}
 	f, err := s.repo.Get(ctx, id)
 	if errors.Is(err, repository.ErrNotFound) {
-		return nil, ErrNotFound
+		return nil, filterNotFound(id)
 	}
 	if err != nil {
 		return nil, err
@@ -71,48 +117,133 @@ func (s *service) Get(ctx context.Context, id uuid.UUID) (*models.Filter, error)
 
 func (s *service) Update(ctx context.Context, id uuid.UUID, query types.Query) (*models.Filter, error) {
 	if id == uuid.Nil {
-		return nil, fmt.Errorf("%w: invalid id", ErrValidation)
+		return nil, invalidID("id")
+	}
+	if _, err := s.resolve(ctx, id, query); err != nil {
+		return nil, err
 	}
 
 	f, err := s.repo.Update(ctx, id, query)
 	if errors.Is(err, repository.ErrNotFound) {
-		return nil, ErrNotFound
+		return nil, filterNotFound(id)
+	}
+	if errors.Is(err, repository.ErrUnknownRef) {
+		return nil, unknownRef(err)
 	}
 	return f, err
 }
 
-func (s *service) Delete(ctx context.Context, id uuid.UUID) error {
+func (s *service) Delete(ctx context.Context, id uuid.UUID, force bool) error {
 	if id == uuid.Nil {
-		return fmt.Errorf("%w: invalid id", ErrValidation)
+		return invalidID("id")
 	}
-	err := s.repo.Delete(ctx, id)
+	err := s.repo.Delete(ctx, id, force)
 	if errors.Is(err, repository.ErrNotFound) {
-		return ErrNotFound
+		return filterNotFound(id)
+	}
+	var de *repository.DependentsError
+	if errors.As(err, &de) {
+		return Conflict(CodeFilterReferenced, "filter is referenced by %v", de.Dependents)
 	}
 	return err
 }
 
 func (s *service) Apply(ctx context.Context, id uuid.UUID) (types.Query, error) {
 	if id == uuid.Nil {
-		return nil, fmt.Errorf("%w: invalid id", ErrValidation)
+		return nil, invalidID("id")
+	}
+
+	now := time.Now().In(s.loc)
+	key := applyCacheKey(id, s.currentUserID, now)
+	var gen cache.Generation
+	if s.cache != nil {
+		var (
+			q  types.Query
+			ok bool
+		)
+		if gen, ok = s.cache.Get(ctx, key, &q); ok {
+			return q, nil
+		}
 	}
 
 	f, err := s.repo.Get(ctx, id)
 	if errors.Is(err, repository.ErrNotFound) {
-		return nil, ErrNotFound
+		return nil, filterNotFound(id)
+	}
+	if err != nil {
+		return nil, err
 	}
+
+	resolved, err := s.resolve(ctx, id, f.Query)
 	if err != nil {
 		return nil, err
 	}
 
+	_, span := tracer.Start(ctx, "placeholder.RenderQuery")
+	start := time.Now()
 	q, err := placeholder.RenderQuery(
-		f.Query,
-		time.Now().In(s.loc),
+		resolved,
+		now,
 		s.loc,
 		s.currentUserID,
 	)
+	if s.observeRender != nil {
+		s.observeRender(time.Since(start), err)
+	}
+	endSpan(span, err)
 	if err != nil {
-		return nil, fmt.Errorf("%w: render template: %s", ErrValidation, err)
+		return nil, Invalid(CodeRenderFailed, "render template: "+err.Error(), FieldError{Field: "query", Message: err.Error()})
+	}
+	if s.cache != nil {
+		s.cache.Set(ctx, gen, key, q)
 	}
 	return q, nil
 }
+
+// resolve раскрывает ссылки на другие фильтры; ошибки композиции считаются ошибками валидации.
+func (s *service) resolve(ctx context.Context, id uuid.UUID, query types.Query) (types.Query, error) {
+	return s.resolveWith(ctx, id, query, s.lookup)
+}
+
+func (s *service) lookup(ctx context.Context, id uuid.UUID) (types.Query, error) {
+	f, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return f.Query, nil
+}
+
+func (s *service) resolveWith(ctx context.Context, id uuid.UUID, query types.Query, lookup compose.Lookup) (types.Query, error) {
+	q, err := compose.Resolve(ctx, id, query, lookup, compose.DefaultMaxDepth)
+	switch {
+	case errors.Is(err, repository.ErrNotFound),
+		errors.Is(err, compose.ErrInvalidRef), errors.Is(err, compose.ErrCycle), errors.Is(err, compose.ErrDepth):
+		return nil, Invalid(CodeInvalidQuery, err.Error(), FieldError{Field: "query", Message: err.Error()})
+	case err != nil:
+		return nil, err
+	}
+	return q, nil
+}
+
+func (s *service) Results(ctx context.Context, id uuid.UUID, page backend.Page) ([]backend.Document, string, error) {
+	if s.backend == nil {
+		return nil, "", ErrNoBackend
+	}
+
+	q, err := s.Apply(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	docs, next, err := s.backend.Search(ctx, q, page)
+	switch {
+	case errors.Is(err, backend.ErrInvalidCursor):
+		return nil, "", Invalid(CodeInvalidCursor, err.Error(), FieldError{Field: "cursor", Message: err.Error()})
+	case errors.Is(err, elastic.ErrUnsupported):
+		return nil, "", Invalid(CodeUnsupportedQuery, err.Error())
+	case err != nil:
+		slog.WarnContext(ctx, "search backend error", "filter_id", id, "error", err)
+		return nil, "", fmt.Errorf("%w: %s", ErrBackend, err)
+	}
+	return docs, next, nil
+}
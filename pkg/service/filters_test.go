@@ -0,0 +1,331 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"search-filter/pkg/backend"
+	"search-filter/pkg/bundle"
+	"search-filter/pkg/cache"
+	"search-filter/pkg/repository"
+	"search-filter/pkg/types"
+
+	"github.com/google/uuid"
+)
+
+func newTestService(t *testing.T) Filters {
+	t.Helper()
+	svc, err := NewFiltersService(repository.NewMemoryRepository(), time.UTC, 42)
+	if err != nil {
+		t.Fatalf("NewFiltersService: %v", err)
+	}
+	return svc
+}
+
+func TestNewFiltersServiceValidation(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	tests := []struct {
+		name string
+		repo repository.Repository
+		loc  *time.Location
+		user int64
+	}{
+		{"nil repo", nil, time.UTC, 1},
+		{"nil location", repo, nil, 1},
+		{"non-positive user", repo, time.UTC, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewFiltersService(tt.repo, tt.loc, tt.user); err == nil {
+				t.Error("NewFiltersService() error = nil")
+			}
+		})
+	}
+}
+
+func TestApplyComposes(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+
+	base, err := svc.Create(ctx, "base", types.Query{"tags": []any{"golang"}, "author": "{{current_user}}"})
+	if err != nil {
+		t.Fatalf("Create base: %v", err)
+	}
+	f, err := svc.Create(ctx, "f", types.Query{"$ref": base.ID.String(), "tags": []any{"db"}})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	q, err := svc.Apply(ctx, f.ID)
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if q["author"] != "42" {
+		t.Errorf("author = %v, want 42", q["author"])
+	}
+	if tags, _ := q["tags"].([]any); len(tags) != 2 {
+		t.Errorf("tags = %v, want union of both filters", q["tags"])
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+
+	a, err := svc.Create(ctx, "a", types.Query{"x": "1"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	b, err := svc.Create(ctx, "b", types.Query{"$ref": a.ID.String()})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		call func() error
+		want error
+	}{
+		{"get nil id", func() error { _, err := svc.Get(ctx, uuid.Nil); return err }, ErrValidation},
+		{"get missing", func() error { _, err := svc.Get(ctx, uuid.New()); return err }, ErrNotFound},
+		{"update missing", func() error { _, err := svc.Update(ctx, uuid.New(), types.Query{"x": "1"}); return err }, ErrNotFound},
+		{"apply missing", func() error { _, err := svc.Apply(ctx, uuid.New()); return err }, ErrNotFound},
+		{"create dangling ref", func() error {
+			_, err := svc.Create(ctx, "c", types.Query{"$ref": uuid.NewString()})
+			return err
+		}, ErrValidation},
+		{"update introduces cycle", func() error {
+			_, err := svc.Update(ctx, a.ID, types.Query{"$ref": b.ID.String()})
+			return err
+		}, ErrValidation},
+		{"apply bad template", func() error {
+			f, err := svc.Create(ctx, "bad", types.Query{"x": "{{nope}}"})
+			if err != nil {
+				return err
+			}
+			_, err = svc.Apply(ctx, f.ID)
+			return err
+		}, ErrValidation},
+		{"delete referenced", func() error { return svc.Delete(ctx, a.ID, false) }, ErrConflict},
+		{"delete missing", func() error { return svc.Delete(ctx, uuid.New(), false) }, ErrNotFound},
+		{"results without backend", func() error {
+			_, _, err := svc.Results(ctx, a.ID, backend.Page{})
+			return err
+		}, ErrNoBackend},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if err := svc.Delete(ctx, a.ID, false); err == nil || !strings.Contains(err.Error(), b.ID.String()) {
+		t.Errorf("Delete referenced = %v, want dependents listed", err)
+	}
+}
+
+func TestFromRepository(t *testing.T) {
+	tests := []struct {
+		err  error
+		kind error
+		code string
+	}{
+		{repository.ErrNotFound, ErrNotFound, CodeFilterNotFound},
+		{fmt.Errorf("get: %w", repository.ErrAlreadyExists), ErrConflict, CodeConflict},
+		{repository.ErrHasDependents, ErrConflict, CodeFilterReferenced},
+		{repository.ErrUnknownRef, ErrValidation, CodeInvalidQuery},
+		{repository.ErrUnknownBatchOp, ErrValidation, CodeValidation},
+	}
+	for _, tt := range tests {
+		var se *Error
+		if err := FromRepository(tt.err); !errors.As(err, &se) || se.Kind != tt.kind || se.Code != tt.code {
+			t.Errorf("FromRepository(%v) = %v, want %v with code %s", tt.err, err, tt.kind, tt.code)
+		}
+	}
+
+	if err := FromRepository(repository.ErrRolledBack); !errors.Is(err, ErrAborted) {
+		t.Errorf("FromRepository(ErrRolledBack) = %v, want ErrAborted", err)
+	}
+	other := errors.New("connection reset")
+	if err := FromRepository(other); err != other {
+		t.Errorf("FromRepository(other) = %v, want it unchanged", err)
+	}
+}
+
+func TestBatchRefs(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+
+	x, err := svc.Create(ctx, "x", types.Query{"x": "1"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	y, err := svc.Create(ctx, "y", types.Query{"$ref": x.ID.String()})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	ref := func(id uuid.UUID) types.Query { return types.Query{"$ref": id.String()} }
+	code := func(err error) string {
+		var se *Error
+		if errors.As(err, &se) {
+			return se.Code
+		}
+		return ""
+	}
+
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	res, err := svc.Batch(ctx, []repository.BatchOp{
+		// Ссылка вперёд на фильтр, создаваемый в том же пакете.
+		{Kind: repository.BatchCreate, ID: a, Name: "a", Query: ref(b)},
+		{Kind: repository.BatchCreate, ID: b, Name: "b", Query: types.Query{"b": "1"}},
+		{Kind: repository.BatchCreate, Name: "dangling", Query: ref(uuid.New())},
+		// c ссылается на отклонённую операцию и отклоняется вслед за ней.
+		{Kind: repository.BatchCreate, ID: c, Name: "c", Query: ref(uuid.New())},
+		{Kind: repository.BatchCreate, Name: "via c", Query: ref(c)},
+		// Цикл через уже сохранённый y.
+		{Kind: repository.BatchUpdate, ID: x.ID, Query: ref(y.ID)},
+		{Kind: repository.BatchCreate, ID: x.ID, Name: "duplicate", Query: types.Query{"d": "1"}},
+	}, false)
+	if err != nil {
+		t.Fatalf("Batch: %v", err)
+	}
+	if res[0].Err != nil || res[1].Err != nil {
+		t.Errorf("forward ref = %v, %v", res[0].Err, res[1].Err)
+	}
+	for _, i := range []int{2, 3, 4, 5} {
+		if code(res[i].Err) != CodeInvalidQuery {
+			t.Errorf("op %d: err = %v, want %s", i, res[i].Err, CodeInvalidQuery)
+		}
+	}
+	if !errors.Is(res[6].Err, ErrConflict) || code(res[6].Err) != CodeConflict {
+		t.Errorf("create with existing id: err = %v, want conflict", res[6].Err)
+	}
+	if q, err := svc.Apply(ctx, a); err != nil || q["b"] != "1" {
+		t.Errorf("Apply(a) = %v, %v", q, err)
+	}
+
+	// Цикл между двумя обновлениями одного пакета.
+	res, err = svc.Batch(ctx, []repository.BatchOp{
+		{Kind: repository.BatchUpdate, ID: a, Query: ref(b)},
+		{Kind: repository.BatchUpdate, ID: b, Query: ref(a)},
+	}, true)
+	if err != nil {
+		t.Fatalf("Batch: %v", err)
+	}
+	for i, r := range res {
+		if !errors.Is(r.Err, ErrValidation) && !errors.Is(r.Err, ErrAborted) {
+			t.Errorf("op %d: err = %v, want rejection", i, r.Err)
+		}
+	}
+	if code(res[1].Err) != CodeInvalidQuery {
+		t.Errorf("cycle: err = %v", res[1].Err)
+	}
+
+	// Ссылка на фильтр, удаляемый тем же пакетом.
+	res, err = svc.Batch(ctx, []repository.BatchOp{
+		{Kind: repository.BatchUpdate, ID: y.ID, Query: ref(b)},
+		{Kind: repository.BatchDelete, ID: b, Force: true},
+	}, false)
+	if err != nil {
+		t.Fatalf("Batch: %v", err)
+	}
+	if code(res[0].Err) != CodeInvalidQuery {
+		t.Errorf("ref to deleted filter: err = %v", res[0].Err)
+	}
+}
+
+func TestImportRefs(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+
+	existing, err := svc.Create(ctx, "existing", types.Query{"x": "1"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	a, b := uuid.New(), uuid.New()
+	ref := func(id uuid.UUID) types.Query { return types.Query{"$ref": id.String()} }
+	importOf := func(filters ...bundle.Filter) error {
+		_, err := svc.Import(ctx, &bundle.Bundle{Version: bundle.Version, Filters: filters}, ConflictRename)
+		return err
+	}
+
+	tests := []struct {
+		name    string
+		filters []bundle.Filter
+		wantErr bool
+	}{
+		{"ref inside bundle", []bundle.Filter{{ID: a, Name: "a", Query: ref(b)}, {ID: b, Name: "b", Query: types.Query{"y": "1"}}}, false},
+		{"ref to existing filter", []bundle.Filter{{Name: "c", Query: ref(existing.ID)}}, false},
+		{"dangling ref", []bundle.Filter{{Name: "d", Query: ref(uuid.New())}}, true},
+		{"cycle among renamed filters", []bundle.Filter{{ID: uuid.New(), Name: "e", Query: ref(a)}, {ID: a, Name: "a", Query: ref(b)}, {ID: b, Name: "b", Query: ref(a)}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := importOf(tt.filters...)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("Import: %v", err)
+				}
+				return
+			}
+			var se *Error
+			if !errors.As(err, &se) || se.Code != CodeInvalidQuery {
+				t.Fatalf("err = %v, want %s", err, CodeInvalidQuery)
+			}
+		})
+	}
+
+	items, err := svc.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(items) != 4 {
+		t.Errorf("filters after imports = %d, want 4: rejected bundles must not be stored", len(items))
+	}
+}
+
+func TestApplyCache(t *testing.T) {
+	ctx := context.Background()
+	c := cache.New(cache.NewLRU(100), "test:", time.Minute)
+	repo := repository.NewCachedRepository(repository.NewMemoryRepository(), c)
+	svc, err := NewFiltersService(repo, time.UTC, 42, WithCache(c))
+	if err != nil {
+		t.Fatalf("NewFiltersService: %v", err)
+	}
+
+	base, err := svc.Create(ctx, "base", types.Query{"tags": []any{"golang"}})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	f, err := svc.Create(ctx, "f", types.Query{"$ref": base.ID.String()})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := svc.Apply(ctx, f.ID); err != nil {
+			t.Fatalf("Apply: %v", err)
+		}
+	}
+	if st := c.Stats().Kinds["apply"]; st.Hits != 1 || st.Misses != 1 {
+		t.Errorf("apply stats = %+v, want 1 hit and 1 miss", st)
+	}
+
+	// Изменение фильтра, на который ссылается f, меняет и результат f.
+	if _, err := svc.Update(ctx, base.ID, types.Query{"tags": []any{"db"}}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	q, err := svc.Apply(ctx, f.ID)
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if tags, _ := q["tags"].([]any); len(tags) != 1 || tags[0] != "db" {
+		t.Errorf("tags = %v, want [db]", q["tags"])
+	}
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"search-filter/pkg/backend"
+	"search-filter/pkg/bundle"
+	"search-filter/pkg/models"
+	"search-filter/pkg/repository"
+	"search-filter/pkg/types"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("search-filter/pkg/service")
+
+// TracedFilters открывает спан OpenTelemetry на каждый вызов другого Filters.
+type TracedFilters struct {
+	svc Filters
+}
+
+func NewTracedFilters(svc Filters) *TracedFilters {
+	return &TracedFilters{svc: svc}
+}
+
+func startSpan(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "Filters."+method, trace.WithAttributes(attrs...))
+}
+
+func filterID(id uuid.UUID) attribute.KeyValue {
+	return attribute.String("filter.id", id.String())
+}
+
+// endSpan записывает ошибку в спан и закрывает его. Ошибки клиента (не найдено,
+// невалидный запрос, конфликт) не помечают спан как неуспешный.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrValidation) && !errors.Is(err, ErrConflict) {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
+
+func (t *TracedFilters) Create(ctx context.Context, name string, query types.Query) (*models.Filter, error) {
+	ctx, span := startSpan(ctx, "Create")
+	f, err := t.svc.Create(ctx, name, query)
+	if f != nil {
+		span.SetAttributes(filterID(f.ID))
+	}
+	endSpan(span, err)
+	return f, err
+}
+
+func (t *TracedFilters) List(ctx context.Context) ([]models.FilterListItem, error) {
+	ctx, span := startSpan(ctx, "List")
+	items, err := t.svc.List(ctx)
+	endSpan(span, err)
+	return items, err
+}
+
+func (t *TracedFilters) Get(ctx context.Context, id uuid.UUID) (*models.Filter, error) {
+	ctx, span := startSpan(ctx, "Get", filterID(id))
+	f, err := t.svc.Get(ctx, id)
+	endSpan(span, err)
+	return f, err
+}
+
+func (t *TracedFilters) Update(ctx context.Context, id uuid.UUID, query types.Query) (*models.Filter, error) {
+	ctx, span := startSpan(ctx, "Update", filterID(id))
+	f, err := t.svc.Update(ctx, id, query)
+	endSpan(span, err)
+	return f, err
+}
+
+func (t *TracedFilters) Delete(ctx context.Context, id uuid.UUID, force bool) error {
+	ctx, span := startSpan(ctx, "Delete", filterID(id), attribute.Bool("force", force))
+	err := t.svc.Delete(ctx, id, force)
+	endSpan(span, err)
+	return err
+}
+
+func (t *TracedFilters) Apply(ctx context.Context, id uuid.UUID) (types.Query, error) {
+	ctx, span := startSpan(ctx, "Apply", filterID(id))
+	q, err := t.svc.Apply(ctx, id)
+	endSpan(span, err)
+	return q, err
+}
+
+func (t *TracedFilters) Results(ctx context.Context, id uuid.UUID, page backend.Page) ([]backend.Document, string, error) {
+	ctx, span := startSpan(ctx, "Results", filterID(id), attribute.Int("page.limit", page.Limit))
+	docs, next, err := t.svc.Results(ctx, id, page)
+	endSpan(span, err)
+	return docs, next, err
+}
+
+func (t *TracedFilters) Batch(ctx context.Context, ops []repository.BatchOp, atomic bool) ([]BatchResult, error) {
+	ctx, span := startSpan(ctx, "Batch", attribute.Int("batch.ops", len(ops)), attribute.Bool("batch.atomic", atomic))
+	res, err := t.svc.Batch(ctx, ops, atomic)
+	endSpan(span, err)
+	return res, err
+}
+
+func (t *TracedFilters) Export(ctx context.Context, ids []uuid.UUID) (*bundle.Bundle, error) {
+	ctx, span := startSpan(ctx, "Export", attribute.Int("filters", len(ids)))
+	b, err := t.svc.Export(ctx, ids)
+	endSpan(span, err)
+	return b, err
+}
+
+func (t *TracedFilters) Import(ctx context.Context, b *bundle.Bundle, strategy ConflictStrategy) (*ImportReport, error) {
+	ctx, span := startSpan(ctx, "Import", attribute.String("strategy", string(strategy)))
+	r, err := t.svc.Import(ctx, b, strategy)
+	endSpan(span, err)
+	return r, err
+}
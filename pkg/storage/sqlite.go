@@ -0,0 +1,43 @@
+package storage
+
+import (
+	_ "embed"
+	"log"
+	"net/url"
+
+	"github.com/XSAM/otelsql"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	reform "gopkg.in/reform.v1"
+	"gopkg.in/reform.v1/dialects/sqlite3"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema создаётся при открытии: для одноузловых установок на SQLite
+// отдельный шаг миграций не нужен.
+//
+//go:embed sqlite_schema.sql
+var sqliteSchema string
+
+// MustInitSQLite открывает файл SQLite и создаёт схему. logger == nil отключает журнал SQL.
+func MustInitSQLite(path string, logger reform.Logger) *DBs {
+	q := url.Values{}
+	q.Add("_pragma", "foreign_keys(1)")
+	q.Add("_pragma", "busy_timeout(5000)")
+	q.Add("_pragma", "journal_mode(WAL)")
+
+	sqlDB, err := otelsql.Open("sqlite", "file:"+path+"?"+q.Encode(), traceOptions(semconv.DBSystemNameSQLite)...)
+	if err != nil {
+		log.Fatalf("sqlite open: %v", err)
+	}
+	// SQLite допускает одного писателя; одно соединение исключает SQLITE_BUSY
+	// и делает :memory: общей для всех запросов.
+	sqlDB.SetMaxOpenConns(1)
+
+	if _, err := sqlDB.Exec(sqliteSchema); err != nil {
+		log.Fatalf("sqlite schema: %v", err)
+	}
+
+	reformDB := reform.NewDB(sqlDB, sqlite3.Dialect, logger)
+
+	return &DBs{SQL: sqlDB, Reform: reformDB}
+}
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS filters (
+    id          TEXT      PRIMARY KEY,
+    name        TEXT      NOT NULL,
+    query       BLOB      NOT NULL DEFAULT '{}',
+    created_at  TIMESTAMP NOT NULL,
+    updated_at  TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS filter_dependencies (
+    filter_id   TEXT NOT NULL REFERENCES filters (id) ON DELETE CASCADE,
+    depends_on  TEXT NOT NULL REFERENCES filters (id) DEFERRABLE INITIALLY DEFERRED,
+    PRIMARY KEY (filter_id, depends_on)
+);
+
+CREATE INDEX IF NOT EXISTS filter_dependencies_depends_on_idx ON filter_dependencies (depends_on);
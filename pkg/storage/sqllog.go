@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+)
+
+// SQLLogger — reform.Logger, который пишет запросы в slog. Значения аргументов
+// не выводятся, только их число: в них бывают пользовательские данные. Ошибки
+// пишутся всегда, успешные запросы — с вероятностью sampleRate.
+type SQLLogger struct {
+	logger     *slog.Logger
+	sampleRate float64
+}
+
+func NewSQLLogger(logger *slog.Logger, sampleRate float64) *SQLLogger {
+	return &SQLLogger{logger: logger.With("component", "sql"), sampleRate: sampleRate}
+}
+
+func (l *SQLLogger) Before(query string, args []any) {}
+
+func (l *SQLLogger) After(query string, args []any, d time.Duration, err error) {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		l.logger.Warn("query failed", "query", query, "args", len(args), "duration", d, "error", err)
+		return
+	}
+	if l.sampleRate < 1 && rand.Float64() >= l.sampleRate {
+		return
+	}
+	l.logger.Info("query", "query", query, "args", len(args), "duration", d)
+}
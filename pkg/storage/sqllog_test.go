@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSQLLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	l := NewSQLLogger(logger, 1)
+	l.After("SELECT * FROM filters WHERE id = $1", []any{"secret-arg"}, time.Millisecond, nil)
+	if !strings.Contains(buf.String(), `"query":"SELECT * FROM filters WHERE id = $1"`) || !strings.Contains(buf.String(), `"args":1`) {
+		t.Errorf("query not logged: %s", buf.String())
+	}
+	if strings.Contains(buf.String(), "secret-arg") {
+		t.Errorf("argument values leaked: %s", buf.String())
+	}
+
+	buf.Reset()
+	l = NewSQLLogger(logger, 0)
+	l.After("SELECT 1", nil, time.Millisecond, nil)
+	if buf.Len() != 0 {
+		t.Errorf("sample rate 0 must drop successful queries: %s", buf.String())
+	}
+	l.After("SELECT 1", nil, time.Millisecond, errors.New("boom"))
+	if !strings.Contains(buf.String(), `"error":"boom"`) {
+		t.Errorf("failed queries must always be logged: %s", buf.String())
+	}
+}
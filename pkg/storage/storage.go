@@ -1,11 +1,18 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"log"
-	"os"
+	"sync/atomic"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/XSAM/otelsql"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 	reform "gopkg.in/reform.v1"
 	"gopkg.in/reform.v1/dialects/postgresql"
 )
@@ -13,19 +20,92 @@ import (
 type DBs struct {
 	SQL    *sql.DB
 	Reform *reform.DB
+	// dsn — строка подключения для новых соединений Postgres; nil для SQLite.
+	dsn *atomic.Pointer[string]
 }
 
-func MustInitPostgres(dsn string) *DBs {
-	sqlDB, err := sql.Open("postgres", dsn)
+// SetDSN меняет строку подключения для новых соединений пула, например после
+// ротации пароля. Открытые соединения доживают до ConnMaxLifetime. Для SQLite не действует.
+func (d *DBs) SetDSN(dsn string) {
+	if d.dsn != nil {
+		d.dsn.Store(&dsn)
+	}
+}
+
+// connector открывает каждое соединение с текущей строкой подключения.
+type connector struct {
+	dsn *atomic.Pointer[string]
+}
+
+func (c connector) Connect(ctx context.Context) (driver.Conn, error) {
+	pc, err := pq.NewConnector(*c.dsn.Load())
 	if err != nil {
+		return nil, err
+	}
+	return pc.Connect(ctx)
+}
+
+func (c connector) Driver() driver.Driver {
+	return &pq.Driver{}
+}
+
+// traceOptions включает спаны OpenTelemetry на каждый SQL-запрос, в том числе
+// внутри транзакций reform. Спаны создаются только внутри уже начатой трассы:
+// фоновые опросы (outbox вебхуков и т.п.) не порождают отдельных трасс.
+func traceOptions(system attribute.KeyValue) []otelsql.Option {
+	return []otelsql.Option{
+		otelsql.WithAttributes(system),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitConnPrepare:      true,
+			OmitRows:             true,
+			OmitConnectorConnect: true,
+			DisableErrSkip:       true,
+			SpanFilter: func(ctx context.Context, _ otelsql.Method, _ string, _ []driver.NamedValue) bool {
+				return trace.SpanContextFromContext(ctx).IsValid()
+			},
+		}),
+	}
+}
+
+// Pool — настройки пула соединений sql.DB; нулевые значения оставляют умолчания database/sql.
+type Pool struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+func (p Pool) apply(db *sql.DB) {
+	if p.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(p.MaxOpenConns)
+	}
+	if p.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(p.MaxIdleConns)
+	}
+	if p.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(p.ConnMaxLifetime)
+	}
+	if p.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(p.ConnMaxIdleTime)
+	}
+}
+
+// MustInitPostgres открывает пул соединений с Postgres. logger == nil отключает журнал SQL.
+func MustInitPostgres(dsn string, pool Pool, logger reform.Logger) *DBs {
+	// Ошибку разбора DSN показываем сразу; дальше он читается при каждом подключении.
+	if _, err := pq.NewConnector(dsn); err != nil {
 		log.Fatalf("postgres open: %v", err)
 	}
+	cur := &atomic.Pointer[string]{}
+	cur.Store(&dsn)
+	sqlDB := otelsql.OpenDB(connector{dsn: cur}, traceOptions(semconv.DBSystemNamePostgreSQL)...)
+	pool.apply(sqlDB)
 	if err := sqlDB.Ping(); err != nil {
 		log.Fatalf("postgres ping: %v", err)
 	}
 
-	logger := log.New(os.Stdout, "[SQL] ", log.LstdFlags)
-	reformDB := reform.NewDB(sqlDB, postgresql.Dialect, reform.NewPrintfLogger(logger.Printf))
+	reformDB := reform.NewDB(sqlDB, postgresql.Dialect, logger)
 
-	return &DBs{SQL: sqlDB, Reform: reformDB}
+	return &DBs{SQL: sqlDB, Reform: reformDB, dsn: cur}
 }
@@ -0,0 +1,108 @@
+// Package tracing настраивает OpenTelemetry: глобальный провайдер трассировки
+// с выбранным экспортёром и распространение контекста W3C Trace Context.
+// Пакеты сервиса берут трассировщик через otel.Tracer, поэтому до Setup
+// и при экспортёре none спаны ничего не стоят.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// ServiceName — service.name в ресурсе спанов; OTEL_SERVICE_NAME имеет приоритет.
+const ServiceName = "search-filter"
+
+type Options struct {
+	Exporter string
+	// Endpoint — адрес OTLP/HTTP-коллектора: host:port или URL. Пустой — из
+	// OTEL_EXPORTER_OTLP_ENDPOINT, иначе localhost:4318.
+	Endpoint string
+	// Insecure отключает TLS при подключении к коллектору.
+	Insecure bool
+	// SampleRatio — доля новых трасс, которые записываются; для входящих запросов
+	// решение о записи берётся из traceparent.
+	SampleRatio float64
+	// Writer — вывод экспортёра stdout; nil — os.Stdout.
+	Writer io.Writer
+}
+
+// Setup устанавливает глобальный пропагатор W3C Trace Context и, если экспортёр
+// не none, провайдер трассировки. Возвращаемая функция выгружает накопленные спаны
+// и останавливает провайдер.
+func Setup(ctx context.Context, o Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+
+	exp, err := newExporter(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+	if exp == nil {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(ServiceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("tracing resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(o.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, o Options) (sdktrace.SpanExporter, error) {
+	switch o.Exporter {
+	case ExporterNone, "":
+		return nil, nil
+	case ExporterStdout:
+		w := o.Writer
+		if w == nil {
+			w = os.Stdout
+		}
+		return stdouttrace.New(stdouttrace.WithWriter(w))
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		switch {
+		case strings.Contains(o.Endpoint, "://"):
+			opts = append(opts, otlptracehttp.WithEndpointURL(o.Endpoint))
+		case o.Endpoint != "":
+			opts = append(opts, otlptracehttp.WithEndpoint(o.Endpoint))
+		}
+		if o.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exp, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("otlp exporter: %w", err)
+		}
+		return exp, nil
+	}
+	return nil, fmt.Errorf("tracing exporter %q: want none, stdout or otlp", o.Exporter)
+}
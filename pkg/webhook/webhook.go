@@ -0,0 +1,444 @@
+// Package webhook доставляет события outbox filter_events на зарегистрированные
+// URL с подписью HMAC, повторами с экспоненциальной задержкой и dead-letter.
+package webhook
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var ErrNotFound = errors.New("delivery not found")
+
+// Заголовки запроса. Подпись — hex(HMAC-SHA256(secret, timestamp + "." + body)),
+// получатель должен сверять её и отбрасывать запросы со старым timestamp.
+const (
+	HeaderEvent     = "X-SearchFilter-Event"
+	HeaderDelivery  = "X-SearchFilter-Delivery"
+	HeaderTimestamp = "X-SearchFilter-Timestamp"
+	HeaderSignature = "X-SearchFilter-Signature"
+)
+
+// Endpoint — получатель событий. Пустой Events — все типы событий.
+type Endpoint struct {
+	URL    string
+	Secret string
+	Events []string
+}
+
+func (e Endpoint) wants(typ string) bool {
+	return len(e.Events) == 0 || slices.Contains(e.Events, typ)
+}
+
+// Sign вычисляет значение заголовка X-SearchFilter-Signature.
+func Sign(secret string, ts int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type Dispatcher struct {
+	db        *sql.DB
+	endpoints map[string]Endpoint
+	client    *http.Client
+
+	maxAttempts int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	interval    time.Duration
+	batchSize   int
+}
+
+type Option func(*Dispatcher)
+
+// WithMaxAttempts задаёт число попыток, после которого доставка уходит в dead-letter.
+func WithMaxAttempts(n int) Option {
+	return func(d *Dispatcher) { d.maxAttempts = n }
+}
+
+// WithTimeout ограничивает время одного запроса к получателю.
+func WithTimeout(t time.Duration) Option {
+	return func(d *Dispatcher) { d.client.Timeout = t }
+}
+
+// WithBackoff задаёт задержку перед второй попыткой и её верхнюю границу.
+func WithBackoff(min, max time.Duration) Option {
+	return func(d *Dispatcher) { d.minBackoff, d.maxBackoff = min, max }
+}
+
+// WithInterval задаёт период опроса outbox.
+func WithInterval(t time.Duration) Option {
+	return func(d *Dispatcher) { d.interval = t }
+}
+
+func NewDispatcher(db *sql.DB, endpoints []Endpoint, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		db:          db,
+		endpoints:   map[string]Endpoint{},
+		client:      &http.Client{Timeout: 10 * time.Second},
+		maxAttempts: 10,
+		minBackoff:  time.Second,
+		maxBackoff:  time.Hour,
+		interval:    time.Second,
+		batchSize:   50,
+	}
+	for _, e := range endpoints {
+		d.endpoints[e.URL] = e
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Run рассылает события, пока не отменён ctx. Несколько реплик могут работать
+// одновременно: строки берутся под FOR UPDATE SKIP LOCKED, доставки — в аренду.
+func (d *Dispatcher) Run(ctx context.Context) {
+	t := time.NewTicker(d.interval)
+	defer t.Stop()
+	for {
+		if err := d.Tick(ctx); err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "webhook dispatch failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Tick создаёт доставки для новых событий и выполняет созревшие попытки.
+func (d *Dispatcher) Tick(ctx context.Context) error {
+	if err := d.fanOut(ctx); err != nil {
+		return fmt.Errorf("fan out: %w", err)
+	}
+	if err := d.deliverDue(ctx); err != nil {
+		return fmt.Errorf("deliver: %w", err)
+	}
+	return nil
+}
+
+// fanOut создаёт по доставке на каждого подписанного получателя и отмечает события
+// разосланными. Получатель, добавленный в конфигурацию позже, старых событий не получит.
+func (d *Dispatcher) fanOut(ctx context.Context) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, type FROM filter_events
+		WHERE dispatched_at IS NULL
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`, d.batchSize)
+	if err != nil {
+		return err
+	}
+	type event struct {
+		id  int64
+		typ string
+	}
+	var events []event
+	for rows.Next() {
+		var ev event
+		if err := rows.Scan(&ev.id, &ev.typ); err != nil {
+			rows.Close()
+			return err
+		}
+		events = append(events, ev)
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(events))
+	for _, ev := range events {
+		ids = append(ids, ev.id)
+		for url, e := range d.endpoints {
+			if !e.wants(ev.typ) {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx,
+				"INSERT INTO webhook_deliveries (webhook, event_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+				url, ev.id); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE filter_events SET dispatched_at = now() WHERE id = ANY($1)", pq.Array(ids)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type due struct {
+	id       int64
+	webhook  string
+	eventID  int64
+	attempts int
+	typ      string
+	payload  []byte
+}
+
+// deliverDue выполняет созревшие попытки в три шага, и HTTP-запросы идут вне
+// транзакции: долгая транзакция держала бы xmin, и Events с LastEventID не видели
+// бы новых событий, пока не ответят получатели.
+//
+//  1. Короткой транзакцией доставки захватываются: attempts увеличивается,
+//     next_attempt_at сдвигается на время аренды, чтобы другие реплики их не взяли.
+//  2. Запросы отправляются без транзакции и не дольше аренды.
+//  3. Результаты записываются второй короткой транзакцией. Запись идёт только
+//     при неизменном attempts: если аренда истекла и доставку захватила другая
+//     реплика, её результат не перезаписывается.
+//
+// Если реплика упала после захвата, доставка повторится по истечении аренды, и
+// попытка засчитается — получатель может увидеть событие дважды и должен
+// отбрасывать повторы по X-SearchFilter-Delivery.
+func (d *Dispatcher) deliverDue(ctx context.Context) error {
+	if err := d.dropUnconfigured(ctx); err != nil {
+		return err
+	}
+	lease := d.lease()
+	batch, err := d.claim(ctx, lease)
+	if err != nil || len(batch) == 0 {
+		return err
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, lease)
+	defer cancel()
+	results := make([]error, len(batch))
+	sent := 0
+	for i, it := range batch {
+		if sendCtx.Err() != nil {
+			break
+		}
+		results[i] = d.send(sendCtx, d.endpoints[it.webhook], it)
+		sent++
+	}
+	if ctx.Err() != nil {
+		// Остановка сервера: неотправленные и прерванные доставки повторятся
+		// после аренды, записываются только завершённые.
+		for i := range results[:sent] {
+			if errors.Is(results[i], context.Canceled) {
+				sent = i
+				break
+			}
+		}
+	}
+	return d.record(context.WithoutCancel(ctx), batch[:sent], results[:sent])
+}
+
+// lease — время, на которое захватывается пакет: запросы идут по очереди, и
+// каждый ограничен таймаутом клиента.
+func (d *Dispatcher) lease() time.Duration {
+	if d.client.Timeout <= 0 {
+		return d.maxBackoff
+	}
+	return d.client.Timeout*time.Duration(d.batchSize) + time.Minute
+}
+
+// dropUnconfigured отправляет в dead-letter доставки получателям, удалённым из конфигурации.
+func (d *Dispatcher) dropUnconfigured(ctx context.Context) error {
+	_, err := d.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET dead_at = now(), last_error = 'webhook is no longer configured'
+		WHERE delivered_at IS NULL AND dead_at IS NULL AND NOT (webhook = ANY($1))`,
+		pq.Array(d.urls()))
+	return err
+}
+
+func (d *Dispatcher) urls() []string {
+	urls := make([]string, 0, len(d.endpoints))
+	for url := range d.endpoints {
+		urls = append(urls, url)
+	}
+	return urls
+}
+
+func (d *Dispatcher) claim(ctx context.Context, lease time.Duration) ([]due, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		WITH claimed AS (
+			SELECT id FROM webhook_deliveries
+			WHERE delivered_at IS NULL AND dead_at IS NULL AND next_attempt_at <= now()
+			  AND webhook = ANY($3)
+			ORDER BY event_id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET attempts = d.attempts + 1, next_attempt_at = now() + $2 * interval '1 millisecond'
+		FROM claimed, filter_events e
+		WHERE d.id = claimed.id AND e.id = d.event_id
+		RETURNING d.id, d.webhook, e.id, d.attempts, e.type,
+		          json_build_object('id', e.id, 'type', e.type, 'filter_id', e.filter_id,
+		                            'filter', e.payload, 'created_at', e.created_at)::text`,
+		d.batchSize, lease.Milliseconds(), pq.Array(d.urls()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var batch []due
+	for rows.Next() {
+		var it due
+		if err := rows.Scan(&it.id, &it.webhook, &it.eventID, &it.attempts, &it.typ, &it.payload); err != nil {
+			return nil, err
+		}
+		batch = append(batch, it)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// RETURNING не сохраняет порядок подзапроса.
+	slices.SortFunc(batch, func(a, b due) int { return cmp.Compare(a.eventID, b.eventID) })
+	return batch, nil
+}
+
+func (d *Dispatcher) record(ctx context.Context, batch []due, results []error) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	const claimed = " WHERE id = $1 AND attempts = $2 AND delivered_at IS NULL AND dead_at IS NULL"
+	for i, it := range batch {
+		switch sendErr := results[i]; {
+		case sendErr == nil:
+			_, err = tx.ExecContext(ctx,
+				"UPDATE webhook_deliveries SET last_error = NULL, delivered_at = now()"+claimed,
+				it.id, it.attempts)
+		case it.attempts >= d.maxAttempts:
+			_, err = tx.ExecContext(ctx,
+				"UPDATE webhook_deliveries SET last_error = $3, dead_at = now()"+claimed,
+				it.id, it.attempts, sendErr.Error())
+		default:
+			_, err = tx.ExecContext(ctx,
+				"UPDATE webhook_deliveries SET last_error = $3, next_attempt_at = now() + $4 * interval '1 millisecond'"+claimed,
+				it.id, it.attempts, sendErr.Error(), d.backoff(it.attempts).Milliseconds())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (d *Dispatcher) send(ctx context.Context, e Endpoint, it due) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(it.payload))
+	if err != nil {
+		return err
+	}
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, it.typ)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(it.id, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, Sign(e.Secret, ts, it.payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// backoff — задержка перед попыткой attempts+1: minBackoff·2^(attempts-1) с
+// разбросом ±20%, не больше maxBackoff.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.maxBackoff
+	if attempts-1 < 32 {
+		if b := d.minBackoff << (attempts - 1); b > 0 && b < d.maxBackoff {
+			delay = b
+		}
+	}
+	jitter := time.Duration(float64(delay) * (rand.Float64()*0.4 - 0.2))
+	return delay + jitter
+}
+
+// DeadLetter — доставка, исчерпавшая попытки.
+type DeadLetter struct {
+	ID        int64     `json:"id"`
+	Webhook   string    `json:"webhook"`
+	EventID   int64     `json:"event_id"`
+	EventType string    `json:"event_type"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error"`
+	DeadAt    time.Time `json:"dead_at"`
+}
+
+// DeadLetters возвращает последние limit доставок из dead-letter.
+func (d *Dispatcher) DeadLetters(ctx context.Context, limit int) ([]DeadLetter, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT d.id, d.webhook, d.event_id, e.type, d.attempts, COALESCE(d.last_error, ''), d.dead_at
+		FROM webhook_deliveries d
+		JOIN filter_events e ON e.id = d.event_id
+		WHERE d.dead_at IS NOT NULL
+		ORDER BY d.dead_at DESC, d.id DESC
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []DeadLetter{}
+	for rows.Next() {
+		var dl DeadLetter
+		if err := rows.Scan(&dl.ID, &dl.Webhook, &dl.EventID, &dl.EventType, &dl.Attempts, &dl.LastError, &dl.DeadAt); err != nil {
+			return nil, err
+		}
+		res = append(res, dl)
+	}
+	return res, rows.Err()
+}
+
+// Retry возвращает доставку из dead-letter в очередь с обнулённым счётчиком попыток.
+func (d *Dispatcher) Retry(ctx context.Context, id int64) error {
+	res, err := d.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET dead_at = NULL, attempts = 0, next_attempt_at = now()
+		WHERE id = $1 AND dead_at IS NOT NULL`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
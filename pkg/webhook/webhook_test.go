@@ -0,0 +1,223 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"search-filter/pkg/migrate"
+	"search-filter/pkg/models"
+	"search-filter/pkg/repository"
+	"search-filter/pkg/types"
+
+	_ "github.com/lib/pq"
+	reform "gopkg.in/reform.v1"
+	"gopkg.in/reform.v1/dialects/postgresql"
+)
+
+func TestSign(t *testing.T) {
+	// echo -n '1700000000.{"id":1}' | openssl dgst -sha256 -hmac secret
+	got := Sign("secret", 1700000000, []byte(`{"id":1}`))
+	want := "sha256=" + "3dd1b9aef568d75f6790a84bd2e5dfa1f44409eef3cbdbd3f10b837376100c11"
+	if got != want {
+		t.Errorf("Sign() = %s, want %s", got, want)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDispatcher(nil, nil, WithBackoff(time.Second, time.Minute))
+	tests := []struct {
+		attempts int
+		base     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{5, 16 * time.Second},
+		{7, time.Minute},
+		{100, time.Minute},
+	}
+	for _, tt := range tests {
+		got := d.backoff(tt.attempts)
+		lo, hi := tt.base*8/10, tt.base*12/10
+		if got < lo || got > hi {
+			t.Errorf("backoff(%d) = %s, want within [%s, %s]", tt.attempts, got, lo, hi)
+		}
+	}
+}
+
+// openTestDB открывает Postgres из SEARCHFILTER_TEST_POSTGRES_DSN с пустыми
+// таблицами фильтров и событий.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("SEARCHFILTER_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("SEARCHFILTER_TEST_POSTGRES_DSN is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	ctx := context.Background()
+	if _, err := migrate.Up(ctx, db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, "TRUNCATE filters, filter_events CASCADE"); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+	return db
+}
+
+// TestDispatcher запускается против локального Postgres из SEARCHFILTER_TEST_POSTGRES_DSN.
+func TestDispatcher(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	var (
+		received atomic.Int32
+		failing  atomic.Bool
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+		if r.Header.Get(HeaderSignature) != Sign("s3cret", ts, body) {
+			t.Errorf("bad signature for %s", body)
+		}
+		var ev models.Event
+		if err := json.Unmarshal(body, &ev); err != nil || ev.Type != r.Header.Get(HeaderEvent) {
+			t.Errorf("bad payload %s: %v", body, err)
+		}
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		received.Add(1)
+	}))
+	t.Cleanup(srv.Close)
+
+	repo := repository.NewPostgresRepository(reform.NewDB(db, postgresql.Dialect, nil))
+	f, err := repo.Create(ctx, "hook", types.Query{"a": "b"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := repo.Update(ctx, f.ID, types.Query{"a": "c"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	evs, err := repo.Events(ctx, 0, 10)
+	if err != nil {
+		t.Fatalf("Events: %v", err)
+	}
+	if len(evs) != 2 || evs[0].Type != models.EventFilterCreated || evs[1].Type != models.EventFilterUpdated {
+		t.Fatalf("Events = %+v", evs)
+	}
+
+	d := NewDispatcher(db, []Endpoint{{URL: srv.URL, Secret: "s3cret"}}, WithMaxAttempts(2), WithBackoff(time.Millisecond, time.Millisecond))
+	if err := d.Tick(ctx); err != nil {
+		t.Fatalf("Tick: %v", err)
+	}
+	if received.Load() != 2 {
+		t.Fatalf("received %d deliveries, want 2", received.Load())
+	}
+
+	failing.Store(true)
+	if err := repo.Delete(ctx, f.ID, false); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		time.Sleep(5 * time.Millisecond)
+		if err := d.Tick(ctx); err != nil {
+			t.Fatalf("Tick: %v", err)
+		}
+	}
+	dead, err := d.DeadLetters(ctx, 10)
+	if err != nil {
+		t.Fatalf("DeadLetters: %v", err)
+	}
+	if len(dead) != 1 || dead[0].EventType != models.EventFilterDeleted || dead[0].Attempts != 2 {
+		t.Fatalf("DeadLetters = %+v", dead)
+	}
+
+	failing.Store(false)
+	if err := d.Retry(ctx, dead[0].ID); err != nil {
+		t.Fatalf("Retry: %v", err)
+	}
+	if err := d.Tick(ctx); err != nil {
+		t.Fatalf("Tick: %v", err)
+	}
+	if received.Load() != 3 {
+		t.Errorf("received %d deliveries after retry, want 3", received.Load())
+	}
+	if err := d.Retry(ctx, dead[0].ID); err != ErrNotFound {
+		t.Errorf("Retry delivered = %v, want ErrNotFound", err)
+	}
+}
+
+// Пока получатель отвечает, рассылка не должна держать транзакцию: иначе новые
+// события не видны в Events до конца доставки.
+func TestDispatcherSendsOutsideTransaction(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	arrived := make(chan struct{}, 1)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case arrived <- struct{}{}:
+		default:
+		}
+		<-release
+	}))
+	t.Cleanup(srv.Close)
+
+	repo := repository.NewPostgresRepository(reform.NewDB(db, postgresql.Dialect, nil))
+	if _, err := repo.Create(ctx, "first", types.Query{"a": "b"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	d := NewDispatcher(db, []Endpoint{{URL: srv.URL, Secret: "s3cret"}})
+	done := make(chan error, 1)
+	go func() { done <- d.Tick(ctx) }()
+	select {
+	case <-arrived:
+	case err := <-done:
+		t.Fatalf("Tick returned before delivery: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("delivery did not arrive")
+	}
+
+	f, err := repo.Create(ctx, "second", types.Query{"a": "c"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	evs, err := repo.Events(ctx, 0, 10)
+	close(release)
+	if err != nil {
+		t.Fatalf("Events: %v", err)
+	}
+	if len(evs) != 2 || evs[1].FilterID != f.ID {
+		t.Errorf("Events during delivery = %+v, want both events", evs)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Tick: %v", err)
+	}
+
+	var attempts int
+	var delivered bool
+	if err := db.QueryRowContext(ctx,
+		"SELECT attempts, delivered_at IS NOT NULL FROM webhook_deliveries ORDER BY event_id LIMIT 1").Scan(&attempts, &delivered); err != nil {
+		t.Fatalf("select delivery: %v", err)
+	}
+	if attempts != 1 || !delivered {
+		t.Errorf("delivery attempts = %d, delivered = %v", attempts, delivered)
+	}
+}